@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/reseed"
+	"github.com/codegangsta/cli"
+)
+
+// exportedCert is a certificate laid out the way routers ship them, ex.
+// certificates/reseed/you_at_mail.i2p.crt.
+type exportedCert struct {
+	Label string
+	Name  string // path below the certificates directory
+	Cert  *x509.Certificate
+}
+
+func newKeygenExportCommand() cli.Command {
+	return cli.Command{
+		Name:  "export",
+		Usage: "Export certificates in the layout routers expect, ready to submit upstream",
+		Description: "Copies the certificates created by keygen into certificates/reseed/ and certificates/ssl/,\n" +
+			"   prints their SHA-256 fingerprints and packs them into a tarball. Private keys and CRLs are never exported.",
+		Action: keygenExportAction,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "signer",
+				Usage: "Export the su3 signing certificate for the given signing ID (ex. something@mail.i2p)",
+			},
+			cli.StringFlag{
+				Name:  "tlsHost",
+				Usage: "Export the TLS certificate for the given host",
+			},
+			cli.StringFlag{
+				Name:  "in",
+				Value: ".",
+				Usage: "Directory keygen wrote the certificates to",
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Value: "certificates",
+				Usage: "Certificates directory to export to",
+			},
+			cli.StringFlag{
+				Name:  "tar",
+				Usage: "Path of the tarball to create (default: <signer or host>-certificates.tar.gz)",
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: "Overwrite existing exported certificates and tarball",
+			},
+		},
+	}
+}
+
+func keygenExportAction(c *cli.Context) error {
+	signerID := c.String("signer")
+	tlsHost := c.String("tlsHost")
+
+	if signerID == "" && tlsHost == "" {
+		return cli.NewExitError("You must specify either --tlsHost or --signer", 2)
+	}
+
+	var certs []exportedCert
+	if signerID != "" {
+		cert, err := readExportCert(filepath.Join(c.String("in"), signerFile(signerID)+".crt"))
+		if nil != err {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if cert.Subject.CommonName != signerID {
+			return cli.NewExitError(fmt.Sprintf("Certificate is for '%s', not '%s'", cert.Subject.CommonName, signerID), 1)
+		}
+		certs = append(certs, exportedCert{"Signing", path.Join("reseed", reseed.SignerFilename(signerID)), cert})
+	}
+
+	if tlsHost != "" {
+		cert, err := readExportCert(filepath.Join(c.String("in"), tlsHost+".crt"))
+		if nil != err {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if err := cert.VerifyHostname(tlsHost); nil != err {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		certs = append(certs, exportedCert{"TLS", path.Join("ssl", tlsHost+".crt"), cert})
+	}
+
+	tarFile := c.String("tar")
+	if tarFile == "" {
+		name := tlsHost
+		if signerID != "" {
+			name = signerFile(signerID)
+		}
+		tarFile = name + "-certificates.tar.gz"
+	}
+
+	outDir := c.String("out")
+	if !c.Bool("force") {
+		for _, ec := range certs {
+			if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(ec.Name))); nil == err {
+				return cli.NewExitError(fmt.Sprintf("%s already exists, use --force to overwrite it", filepath.Join(outDir, filepath.FromSlash(ec.Name))), 1)
+			}
+		}
+		if _, err := os.Stat(tarFile); nil == err {
+			return cli.NewExitError(fmt.Sprintf("%s already exists, use --force to overwrite it", tarFile), 1)
+		}
+	}
+
+	var fingerprints bytes.Buffer
+	for _, ec := range certs {
+		file := filepath.Join(outDir, filepath.FromSlash(ec.Name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); nil != err {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if err := ioutil.WriteFile(file, certPem(ec.Cert), 0644); nil != err {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		fp := certFingerprint(ec.Cert)
+		fmt.Printf("\t%s certificate exported to: %s\n", ec.Label, file)
+		fmt.Printf("\t  SHA-256 fingerprint: %s\n", fp)
+		if time.Now().After(ec.Cert.NotAfter) {
+			fmt.Printf("\t  warning: expired on %s\n", ec.Cert.NotAfter.Format(time.RFC3339))
+		}
+		fmt.Fprintf(&fingerprints, "%s  certificates/%s\n", fp, ec.Name)
+	}
+
+	if err := writeExportTar(tarFile, certs, fingerprints.Bytes()); nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	fmt.Printf("\tTarball for upstream saved to: %s\n", tarFile)
+	return nil
+}
+
+func readExportCert(file string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if nil != err {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if nil == block || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s does not contain a PEM certificate", file)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func certPem(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// certFingerprint returns the SHA-256 of the DER certificate in the colon
+// separated form openssl and the router console print.
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+// writeExportTar packs the certificates below certificates/ together with a
+// list of their fingerprints.
+func writeExportTar(file string, certs []exportedCert, fingerprints []byte) error {
+	out, err := os.Create(file)
+	if nil != err {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); nil != err {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	for _, ec := range certs {
+		if err := add("certificates/"+ec.Name, certPem(ec.Cert)); nil != err {
+			return err
+		}
+	}
+	if err := add("fingerprints.txt", fingerprints); nil != err {
+		return err
+	}
+
+	if err := tw.Close(); nil != err {
+		return err
+	}
+	if err := gz.Close(); nil != err {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MDrollette/i2p-tools/su3"
+)
+
+const (
+	// limits guarding against zip and gzip bombs
+	maxExtractSize  = 512 << 20
+	maxExtractFiles = 10000
+)
+
+// extractSu3 writes the content of an su3 file to out based on its file
+// type. Zip files are unpacked into the directory out, gzip files are
+// decompressed and everything else is written as is.
+func extractSu3(su3File *su3.File, out string) (string, error) {
+	if out == "" {
+		out = defaultExtractPath(su3File.FileType)
+	}
+
+	switch su3File.FileType {
+	case su3.FileTypeZIP:
+		return out, unzipContent(su3File.Content, out)
+	case su3.FileTypeXMLGZ, su3.FileTypeTXTGZ:
+		return out, gunzipContent(su3File.Content, out)
+	default:
+		return out, ioutil.WriteFile(out, su3File.Content, 0644)
+	}
+}
+
+func defaultExtractPath(fileType su3.FileType) string {
+	switch fileType {
+	case su3.FileTypeZIP:
+		return "extracted"
+	case su3.FileTypeXMLGZ:
+		return "extracted.xml"
+	case su3.FileTypeTXTGZ:
+		return "extracted.txt"
+	}
+
+	if fileType.Known() {
+		return "extracted." + fileType.String()
+	}
+	return "extracted.bin"
+}
+
+func unzipContent(content []byte, dir string) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if nil != err {
+		return err
+	}
+
+	if len(zipReader.File) > maxExtractFiles {
+		return fmt.Errorf("zip contains too many files: %d", len(zipReader.File))
+	}
+
+	// check every path before writing anything
+	paths := make([]string, len(zipReader.File))
+	for i, f := range zipReader.File {
+		if paths[i], err = extractPath(dir, f.Name); nil != err {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); nil != err {
+		return err
+	}
+
+	var remaining int64 = maxExtractSize
+	for i, f := range zipReader.File {
+		path := paths[i]
+
+		switch {
+		case f.FileInfo().IsDir():
+			if err := os.MkdirAll(path, 0755); nil != err {
+				return err
+			}
+			continue
+		case !f.Mode().IsRegular():
+			fmt.Printf("Skipping non-regular file '%s'\n", f.Name)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); nil != err {
+			return err
+		}
+
+		rc, err := f.Open()
+		if nil != err {
+			return err
+		}
+		n, err := writeLimited(path, rc, remaining)
+		rc.Close()
+		if nil != err {
+			return err
+		}
+		remaining -= n
+	}
+
+	return nil
+}
+
+func gunzipContent(content []byte, path string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if nil != err {
+		return err
+	}
+	defer gz.Close()
+
+	_, err = writeLimited(path, gz, maxExtractSize)
+	return err
+}
+
+// extractPath joins name to dir and makes sure the result stays inside dir.
+func extractPath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+
+	rel, err := filepath.Rel(dir, path)
+	if nil != err || filepath.IsAbs(name) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal file path in zip: %s", name)
+	}
+
+	return path, nil
+}
+
+// writeLimited copies r to a new file at path and fails once more than limit
+// bytes have been written.
+func writeLimited(path string, r io.Reader, limit int64) (int64, error) {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if nil != err {
+		return 0, err
+	}
+	defer out.Close()
+
+	n, err := io.Copy(out, io.LimitReader(r, limit+1))
+	if nil != err {
+		return n, err
+	}
+	if n > limit {
+		return n, fmt.Errorf("extracted content exceeds %d bytes", int64(maxExtractSize))
+	}
+
+	return n, nil
+}
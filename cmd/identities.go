@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/reseed"
+	"github.com/codegangsta/cli"
+)
+
+// identityConfig is one entry of the --identities file, ex.
+//
+//	[
+//	  {"signer": "old@mail.i2p", "key": "old_at_mail.i2p.pem", "notAfter": "2026-11-01T00:00:00Z"},
+//	  {"signer": "new@mail.i2p", "key": "new_at_mail.i2p.pem", "keyPassEnv": "NEW_PASS", "notBefore": "2026-10-20T00:00:00Z"}
+//	]
+//
+// Instead of a key an identity can point to the socket of a signing agent.
+// An encrypted key takes its passphrase from the identity's keyPassEnv or
+// keyPassFd, then from --keyPassEnv or --keyPassFd, and is prompted for
+// otherwise.
+type identityConfig struct {
+	Signer     string    `json:"signer"`
+	Key        string    `json:"key"`
+	KeyPassEnv string    `json:"keyPassEnv"`
+	KeyPassFd  *int      `json:"keyPassFd"`
+	Socket     string    `json:"socket"`
+	NotBefore  time.Time `json:"notBefore"`
+	NotAfter   time.Time `json:"notAfter"`
+}
+
+// keyPassphrases keeps one passphraseFunc per key file and passphrase
+// source, so identities don't share a passphrase unless they share its
+// source, and a reload doesn't ask again.
+type keyPassphrases struct {
+	c     *cli.Context
+	funcs map[string]passphraseFunc
+}
+
+func newKeyPassphrases(c *cli.Context) *keyPassphrases {
+	return &keyPassphrases{c: c, funcs: make(map[string]passphraseFunc)}
+}
+
+func (kp *keyPassphrases) get(keyFile string, config identityConfig) passphraseFunc {
+	var (
+		key        string
+		passphrase passphraseFunc
+	)
+	switch {
+	case config.KeyPassEnv != "":
+		key = "env:" + config.KeyPassEnv
+		passphrase = func() ([]byte, error) { return passphraseFromEnv(config.KeyPassEnv) }
+	case nil != config.KeyPassFd:
+		fd := *config.KeyPassFd
+		key = fmt.Sprintf("fd:%d", fd)
+		passphrase = cachePassphrase(func() ([]byte, error) { return passphraseFromFd(fd) })
+	case hasPassphraseSource(kp.c):
+		key = "flags"
+		passphrase = newPassphraseFunc(kp.c, false)
+	default:
+		key = "prompt:" + keyFile
+		passphrase = newPromptingPassphraseFunc(kp.c, fmt.Sprintf("Passphrase for %s: ", keyFile), false)
+	}
+
+	if cached, ok := kp.funcs[key]; ok {
+		return cached
+	}
+	kp.funcs[key] = passphrase
+	return passphrase
+}
+
+func loadIdentities(path string, passphrases *keyPassphrases) ([]reseed.SigningIdentity, error) {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return nil, err
+	}
+
+	var configs []identityConfig
+	if err := json.Unmarshal(data, &configs); nil != err {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if 0 == len(configs) {
+		return nil, fmt.Errorf("%s: no signing identities", path)
+	}
+
+	var identities []reseed.SigningIdentity
+	for _, config := range configs {
+		id := reseed.SigningIdentity{
+			Name:      config.Signer,
+			NotBefore: config.NotBefore,
+			NotAfter:  config.NotAfter,
+		}
+
+		switch {
+		case config.Socket != "":
+			id.Signer = reseed.NewRemoteSigner(config.Socket)
+			if id.Name == "" {
+				id.Name = config.Socket
+			}
+		case config.Signer != "":
+			keyFile := config.Key
+			if keyFile == "" {
+				keyFile = signerFile(config.Signer) + ".pem"
+			}
+			privKey, err := loadPrivateKey(keyFile, passphrases.get(keyFile, config))
+			if nil != err {
+				return nil, err
+			}
+			id.Signer = reseed.NewKeySigner(privKey, []byte(config.Signer))
+		default:
+			return nil, fmt.Errorf("%s: every identity needs a signer or a socket", path)
+		}
+
+		identities = append(identities, id)
+	}
+
+	return identities, nil
+}
+
+// watchIdentities reloads the identities file on SIGHUP and rebuilds the su3
+// cache whenever the set of identities or the active one changes.
+func watchIdentities(path string, passphrases *keyPassphrases, signer *reseed.RotatingSigner, reseeder *reseed.ReseederImpl) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for {
+		var change <-chan time.Time
+		if next := signer.NextChange(time.Now()); !next.IsZero() {
+			change = time.After(time.Until(next))
+		}
+
+		select {
+		case <-hup:
+			identities, err := loadIdentities(path, passphrases)
+			if nil != err {
+				log.Printf("Keeping current signing identities, reload failed: %s\n", err)
+				continue
+			}
+			if !reseed.NewRotatingSigner(identities).HasActive(time.Now()) && signer.HasActive(time.Now()) {
+				log.Printf("Keeping current signing identities, none of the %d in %s is active\n", len(identities), path)
+				continue
+			}
+			signer.SetIdentities(identities)
+			log.Printf("Reloaded %d signing identities from %s\n", len(identities), path)
+		case <-change:
+			log.Println("Signing identity window changed")
+		}
+
+		// without an active identity every su3 would fail to sign
+		if !signer.HasActive(time.Now()) {
+			log.Println("WARNING: no signing identity is active, serving the current su3 files until one is")
+			continue
+		}
+
+		if err := reseeder.Rebuild(); nil != err {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/reseed"
+	"github.com/MDrollette/i2p-tools/su3"
+	"github.com/codegangsta/cli"
+)
+
+func NewSu3InspectCommand() cli.Command {
+	return cli.Command{
+		Name:        "inspect",
+		Usage:       "List the routerInfos in a reseed Su3 file",
+		Description: "Verify a reseed Su3 file and list every routerInfo it contains with its hash, zip time, size, published date and capabilities.",
+		ArgsUsage:   "<file>",
+		Action:      su3InspectAction,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "certs",
+				Value: "./certificates",
+				Usage: "Path to the certificates directory (with reseed/, news/, plugin/ and router/ subdirectories)",
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the routerInfos and totals as JSON",
+			},
+		},
+	}
+}
+
+// seedReport is one routerInfo as printed by inspect --json.
+type seedReport struct {
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash,omitempty"`
+	ZipTime   time.Time  `json:"zipTime"`
+	Size      int        `json:"size"`
+	Published *time.Time `json:"published,omitempty"`
+	Caps      string     `json:"caps"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// seedTotals summarizes the routerInfos of a reseed bundle.
+type seedTotals struct {
+	RouterInfos     int        `json:"routerInfos"`
+	Size            int        `json:"size"`
+	Unparsable      int        `json:"unparsable"`
+	Floodfill       int        `json:"floodfill"`
+	Reachable       int        `json:"reachable"`
+	Unreachable     int        `json:"unreachable"`
+	OldestPublished *time.Time `json:"oldestPublished,omitempty"`
+	NewestPublished *time.Time `json:"newestPublished,omitempty"`
+}
+
+type inspectReport struct {
+	File        string        `json:"file"`
+	SignerID    string        `json:"signerId"`
+	Version     string        `json:"version"`
+	RouterInfos []*seedReport `json:"routerInfos"`
+	Totals      seedTotals    `json:"totals"`
+}
+
+func su3InspectAction(c *cli.Context) error {
+	if 1 != len(c.Args()) {
+		return cli.NewExitError("You must specify exactly one su3 file", 2)
+	}
+	path := c.Args().First()
+
+	ks := &reseed.KeyStore{Path: c.String("certs")}
+	su3File := new(su3.File)
+	if err := verifySu3(path, su3File, ks, 0); nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	if su3File.ContentType != su3.ContentTypeReseed || su3File.FileType != su3.FileTypeZIP {
+		return cli.NewExitError(fmt.Sprintf("%s is not a reseed bundle (%s, %s)", path, su3File.ContentType, su3File.FileType), 1)
+	}
+
+	seeds, err := reseed.InspectSeeds(su3File.Content)
+	if nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	report := &inspectReport{
+		File:     path,
+		SignerID: string(su3File.SignerID),
+		Version:  strings.Trim(string(su3File.Version), "\x00"),
+	}
+	for i := range seeds {
+		seed := &seeds[i]
+		sr := &seedReport{
+			Name:    seed.Name,
+			Hash:    seed.Hash,
+			ZipTime: seed.ZipTime,
+			Size:    seed.Size,
+			Caps:    seed.Caps,
+		}
+		report.Totals.add(seed)
+		if nil != seed.Err {
+			sr.Error = seed.Err.Error()
+		} else {
+			sr.Published = &seed.Published
+		}
+		report.RouterInfos = append(report.RouterInfos, sr)
+	}
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return nil
+	}
+
+	printInspectReport(os.Stdout, report)
+	return nil
+}
+
+func (t *seedTotals) add(seed *reseed.SeedInfo) {
+	t.RouterInfos++
+	t.Size += seed.Size
+	if nil != seed.Err {
+		t.Unparsable++
+		return
+	}
+
+	if strings.ContainsRune(seed.Caps, 'f') {
+		t.Floodfill++
+	}
+	if strings.ContainsRune(seed.Caps, 'R') {
+		t.Reachable++
+	}
+	if strings.ContainsRune(seed.Caps, 'U') {
+		t.Unreachable++
+	}
+
+	published := seed.Published
+	if nil == t.OldestPublished || published.Before(*t.OldestPublished) {
+		t.OldestPublished = &published
+	}
+	if nil == t.NewestPublished || published.After(*t.NewestPublished) {
+		t.NewestPublished = &published
+	}
+}
+
+func printInspectReport(w io.Writer, report *inspectReport) {
+	fmt.Fprintf(w, "%s: signed by '%s', version %s\n\n", report.File, report.SignerID, report.Version)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HASH\tZIP TIME\tSIZE\tPUBLISHED\tCAPS")
+	for _, r := range report.RouterInfos {
+		if "" != r.Error {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t-\t(%s)\n", r.Name, formatTime(&r.ZipTime), r.Size, r.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", r.Hash, formatTime(&r.ZipTime), r.Size, formatTime(r.Published), r.Caps)
+	}
+	tw.Flush()
+
+	t := report.Totals
+	fmt.Fprintf(w, "\n%d routerInfos, %d bytes, %d floodfill, %d reachable, %d unreachable, %d unparsable\n",
+		t.RouterInfos, t.Size, t.Floodfill, t.Reachable, t.Unreachable, t.Unparsable)
+	if nil != t.OldestPublished {
+		fmt.Fprintf(w, "published between %s and %s\n", formatTime(t.OldestPublished), formatTime(t.NewestPublished))
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if nil == t || t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
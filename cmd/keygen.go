@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"crypto/x509/pkix"
 	"fmt"
 
 	"github.com/codegangsta/cli"
@@ -11,16 +12,76 @@ func NewKeygenCommand() cli.Command {
 		Name:   "keygen",
 		Usage:  "Generate keys for reseed su3 signing and TLS serving.",
 		Action: keygenAction,
-		Flags: []cli.Flag{
+		Subcommands: []cli.Command{
+			newKeygenExportCommand(),
+		},
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:  "signer",
 				Usage: "Generate a private key and certificate for the given su3 signing ID (ex. something@mail.i2p)",
 			},
+			cli.StringFlag{
+				Name:  "signerAlgo",
+				Value: defaultSignerOptions().Algo,
+				Usage: "Key algorithm for the su3 signing key (RSA-2048, RSA-3072, RSA-4096, ECDSA-P256, ECDSA-P384, ECDSA-P521 or Ed25519)",
+			},
+			cli.StringFlag{
+				Name:  "signerValidity",
+				Value: "10y",
+				Usage: "Validity period of the signing certificate (ex. 10y, 365d, 8760h)",
+			},
+			cli.BoolFlag{
+				Name:  "encryptKey",
+				Usage: "Encrypt the su3 signing key with a passphrase (see --keyPassEnv and --keyPassFd)",
+			},
 			cli.StringFlag{
 				Name:  "tlsHost",
 				Usage: "Generate a self-signed TLS certificate and private key for the given host",
 			},
-		},
+			cli.StringFlag{
+				Name:  "tlsAlgo",
+				Value: defaultTLSOptions().Algo,
+				Usage: "Key algorithm for the TLS key (RSA-2048, RSA-3072, RSA-4096, ECDSA-P256, ECDSA-P384 or ECDSA-P521)",
+			},
+			cli.StringFlag{
+				Name:  "tlsValidity",
+				Value: "5y",
+				Usage: "Validity period of the TLS certificate (ex. 5y, 90d, 2160h)",
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Value: ".",
+				Usage: "Directory to write the keys, certificates and CRLs to",
+			},
+			cli.StringFlag{
+				Name:  "organization",
+				Usage: "Subject organization (default: I2P Anonymous Network)",
+			},
+			cli.StringFlag{
+				Name:  "organizationalUnit",
+				Usage: "Subject organizational unit (default: I2P)",
+			},
+			cli.StringFlag{
+				Name:  "country",
+				Usage: "Subject country (default: XX)",
+			},
+			cli.StringFlag{
+				Name:  "province",
+				Usage: "Subject state or province",
+			},
+			cli.StringFlag{
+				Name:  "locality",
+				Usage: "Subject locality (default: XX)",
+			},
+			cli.StringFlag{
+				Name:  "streetAddress",
+				Usage: "Subject street address (default: XX)",
+			},
+			cli.BoolFlag{
+				Name:  "force",
+				Usage: "Overwrite existing keys and certificates",
+			},
+		}, passphraseFlags...),
 	}
 }
 
@@ -33,15 +94,51 @@ func keygenAction(c *cli.Context) {
 		return
 	}
 
+	var subject pkix.Name
+	for _, f := range []struct {
+		flag  string
+		field *[]string
+	}{
+		{"organization", &subject.Organization},
+		{"organizationalUnit", &subject.OrganizationalUnit},
+		{"country", &subject.Country},
+		{"province", &subject.Province},
+		{"locality", &subject.Locality},
+		{"streetAddress", &subject.StreetAddress},
+	} {
+		if value := c.String(f.flag); value != "" {
+			*f.field = []string{value}
+		}
+	}
+
 	if signerID != "" {
-		if err := createSigningCertificate(signerID); nil != err {
+		var passphrase passphraseFunc
+		if c.Bool("encryptKey") {
+			passphrase = newPassphraseFunc(c, true)
+		}
+
+		opts := certOptions{
+			Algo:     c.String("signerAlgo"),
+			Validity: c.String("signerValidity"),
+			OutDir:   c.String("out"),
+			Subject:  subject,
+			Force:    c.Bool("force"),
+		}
+		if err := createSigningCertificate(signerID, opts, passphrase); nil != err {
 			fmt.Println(err)
 			return
 		}
 	}
 
 	if tlsHost != "" {
-		if err := createTLSCertificate(tlsHost); nil != err {
+		opts := certOptions{
+			Algo:     c.String("tlsAlgo"),
+			Validity: c.String("tlsValidity"),
+			OutDir:   c.String("out"),
+			Subject:  subject,
+			Force:    c.Bool("force"),
+		}
+		if err := createTLSCertificate(tlsHost, opts); nil != err {
 			fmt.Println(err)
 			return
 		}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// passphraseFunc returns the passphrase for a signing key. It is only called
+// when a key is actually encrypted (or is about to be).
+type passphraseFunc func() ([]byte, error)
+
+// passphraseFlags select where the signing key passphrase comes from. When
+// neither is given the passphrase is prompted for on a terminal.
+var passphraseFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "keyPassEnv",
+		Usage: "Name of an environment variable holding the signing key passphrase",
+	},
+	cli.IntFlag{
+		Name:  "keyPassFd",
+		Value: -1,
+		Usage: "File descriptor to read the signing key passphrase from (ex. 3)",
+	},
+}
+
+// newPassphraseFunc builds a passphraseFunc from the passphraseFlags. When
+// confirm is set a prompted passphrase has to be entered twice.
+func newPassphraseFunc(c *cli.Context, confirm bool) passphraseFunc {
+	return newPromptingPassphraseFunc(c, "Signing key passphrase: ", confirm)
+}
+
+// hasPassphraseSource reports whether --keyPassEnv or --keyPassFd is given.
+func hasPassphraseSource(c *cli.Context) bool {
+	return c.String("keyPassEnv") != "" || c.Int("keyPassFd") >= 0
+}
+
+// newPromptingPassphraseFunc is newPassphraseFunc with the prompt to show
+// when the passphrase is read from the terminal.
+func newPromptingPassphraseFunc(c *cli.Context, prompt string, confirm bool) passphraseFunc {
+	return cachePassphrase(func() ([]byte, error) {
+		switch {
+		case c.String("keyPassEnv") != "":
+			return passphraseFromEnv(c.String("keyPassEnv"))
+		case c.Int("keyPassFd") >= 0:
+			return passphraseFromFd(c.Int("keyPassFd"))
+		case c.Bool("noPrompt") || !isTerminal(os.Stdin):
+			return nil, errors.New("the signing key is encrypted, use --keyPassEnv or --keyPassFd to give its passphrase")
+		default:
+			return promptPassphrase(prompt, confirm)
+		}
+	})
+}
+
+// cachePassphrase remembers the first passphrase f returns.
+func cachePassphrase(f passphraseFunc) passphraseFunc {
+	var cached []byte
+
+	return func() ([]byte, error) {
+		if nil != cached {
+			return cached, nil
+		}
+
+		pass, err := f()
+		if nil != err {
+			return nil, err
+		}
+
+		cached = pass
+		return pass, nil
+	}
+}
+
+func passphraseFromEnv(name string) ([]byte, error) {
+	pass := os.Getenv(name)
+	if pass == "" {
+		return nil, fmt.Errorf("environment variable %s is empty", name)
+	}
+	return []byte(pass), nil
+}
+
+func passphraseFromFd(fd int) ([]byte, error) {
+	f := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	if nil == f {
+		return nil, fmt.Errorf("invalid file descriptor %d", fd)
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		if nil == err {
+			err = errors.New("empty passphrase")
+		}
+		return nil, fmt.Errorf("unable to read passphrase from fd %d: %s", fd, err)
+	}
+
+	return []byte(line), nil
+}
+
+func promptPassphrase(prompt string, confirm bool) ([]byte, error) {
+	pass, err := readHidden(prompt)
+	if nil != err {
+		return nil, err
+	}
+	if 0 == len(pass) {
+		return nil, errors.New("empty passphrase")
+	}
+
+	if confirm {
+		again, err := readHidden("Repeat passphrase: ")
+		if nil != err {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+
+	return pass, nil
+}
+
+// readHidden reads a line from stdin with terminal echo turned off. It fails
+// rather than read the passphrase when echo can't be turned off.
+func readHidden(prompt string) ([]byte, error) {
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if err := stty("-echo"); nil != err {
+		return nil, fmt.Errorf("unable to turn off terminal echo (%s), use --keyPassEnv or --keyPassFd instead", err)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	defer func() {
+		stty("echo")
+		fmt.Fprintln(os.Stderr)
+	}()
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" && nil != err {
+		return nil, fmt.Errorf("unable to read passphrase: %s", err)
+	}
+
+	return []byte(line), nil
+}
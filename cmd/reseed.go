@@ -16,11 +16,19 @@ func NewReseedCommand() cli.Command {
 		Name:   "reseed",
 		Usage:  "Start a reseed server",
 		Action: reseedAction,
-		Flags: []cli.Flag{
+		Flags: append([]cli.Flag{
 			cli.StringFlag{
 				Name:  "signer",
 				Usage: "Your su3 signing ID (ex. something@mail.i2p)",
 			},
+			cli.StringFlag{
+				Name:  "signerSocket",
+				Usage: "Path to the unix socket of a signing agent (see the signer command). The signing key is not loaded when set",
+			},
+			cli.StringFlag{
+				Name:  "identities",
+				Usage: "Path to a JSON file listing signing identities with activation windows, reloaded on SIGHUP. Replaces --signer and --key",
+			},
 			cli.StringFlag{
 				Name:  "tlsHost",
 				Usage: "The public hostname used on your TLS certificate",
@@ -85,7 +93,58 @@ func NewReseedCommand() cli.Command {
 				Value: 0,
 				Usage: "Periodically print memory stats.",
 			},
-		},
+			cli.BoolTFlag{
+				Name:  "excludeUnreachable",
+				Usage: "Leave out routers that publish themselves as unreachable (U). Use --excludeUnreachable=false to include them",
+			},
+			cli.BoolTFlag{
+				Name:  "excludeHidden",
+				Usage: "Leave out hidden routers (H). Use --excludeHidden=false to include them",
+			},
+			cli.StringFlag{
+				Name:  "minBandwidth",
+				Usage: "Lowest bandwidth class to include (K, L, M, N, O, P or X)",
+			},
+			cli.DurationFlag{
+				Name:  "maxRiAge",
+				Value: 192 * time.Hour,
+				Usage: "Leave out routerInfos published longer ago than this",
+			},
+			cli.StringFlag{
+				Name:  "minVersion",
+				Usage: "Lowest router.version to include (ex. 0.9.58)",
+			},
+			cli.BoolFlag{
+				Name:  "excludeUnversioned",
+				Usage: "Leave out routers that don't publish a router.version",
+			},
+			cli.Float64Flag{
+				Name:  "floodfillShare",
+				Value: 0,
+				Usage: "Share of floodfills to aim for in each su3 (ex. 0.3, 0 = no target)",
+			},
+			cli.IntFlag{
+				Name:  "maxPerSubnet",
+				Value: reseed.NewSelectionPolicy().MaxPerSubnet,
+				Usage: "Most routers from one IPv4 /16 or IPv6 /48 in each su3 (0 = no limit)",
+			},
+			cli.BoolFlag{
+				Name:  "strictSubnets",
+				Usage: "Never go over --maxPerSubnet, even if su3 files end up with fewer than --numRi routers",
+			},
+			cli.BoolTFlag{
+				Name:  "balanceAddresses",
+				Usage: "Spread each su3 evenly over IPv4/IPv6 and NTCP2/SSU2 addresses. Use --balanceAddresses=false to pick at random",
+			},
+			cli.BoolFlag{
+				Name:  "noPrompt, no-prompt",
+				Usage: "Never prompt; fail when a key or certificate is missing",
+			},
+			cli.BoolFlag{
+				Name:  "generateMissing, generate-missing",
+				Usage: "Never prompt; generate missing keys and certificates",
+			},
+		}, passphraseFlags...),
 	}
 }
 
@@ -98,11 +157,19 @@ func reseedAction(c *cli.Context) {
 	}
 
 	signerID := c.String("signer")
-	if signerID == "" {
+	signerSocket := c.String("signerSocket")
+	identitiesFile := c.String("identities")
+	if signerID == "" && signerSocket == "" && identitiesFile == "" {
 		fmt.Println("--signer is required")
 		return
 	}
 
+	policy, err := newMissingPolicy(c)
+	if nil != err {
+		fmt.Println(err)
+		return
+	}
+
 	var tlsCert, tlsKey string
 	tlsHost := c.String("tlsHost")
 	if tlsHost != "" {
@@ -119,7 +186,7 @@ func reseedAction(c *cli.Context) {
 		}
 
 		// prompt to create tls keys if they don't exist?
-		err := checkOrNewTLSCert(tlsHost, &tlsCert, &tlsKey)
+		err := checkOrNewTLSCert(tlsHost, &tlsCert, &tlsKey, policy)
 		if nil != err {
 			log.Fatalln(err)
 		}
@@ -131,30 +198,94 @@ func reseedAction(c *cli.Context) {
 		return
 	}
 
-	signerKey := c.String("key")
-	// if no key is specified, default to the signerID.pem in the current dir
-	if signerKey == "" {
-		signerKey = signerFile(signerID) + ".pem"
+	minBandwidth, err := reseed.ParseBandwidthClass(c.String("minBandwidth"))
+	if nil != err {
+		fmt.Println(err)
+		return
+	}
+	var minVersion reseed.RouterVersion
+	if "" != c.String("minVersion") {
+		minVersion, err = reseed.ParseRouterVersion(c.String("minVersion"))
+		if nil != err {
+			fmt.Println(err)
+			return
+		}
+	}
+	if share := c.Float64("floodfillShare"); share < 0 || share > 1 {
+		fmt.Println("--floodfillShare must be between 0 and 1")
+		return
 	}
 
-	// load our signing privKey
-	privKey, err := getOrNewSigningCert(&signerKey, signerID)
-	if nil != err {
-		log.Fatalln(err)
+	var (
+		signer      reseed.Su3Signer
+		rotating    *reseed.RotatingSigner
+		passphrases *keyPassphrases
+	)
+	if identitiesFile != "" {
+		passphrases = newKeyPassphrases(c)
+		identities, err := loadIdentities(identitiesFile, passphrases)
+		if nil != err {
+			log.Fatalln(err)
+		}
+		rotating = reseed.NewRotatingSigner(identities)
+		if !rotating.HasActive(time.Now()) {
+			log.Printf("WARNING: none of the signing identities in %s is active, su3 files can't be signed until one is\n", identitiesFile)
+		}
+		signer = rotating
+	} else if signerSocket != "" {
+		// the key is held by a separate signing agent
+		signer = reseed.NewRemoteSigner(signerSocket)
+	} else {
+		signerKey := c.String("key")
+		// if no key is specified, default to the signerID.pem in the current dir
+		if signerKey == "" {
+			signerKey = signerFile(signerID) + ".pem"
+		}
+
+		// a generated key is encrypted when there is a passphrase to use,
+		// loading it then reuses the passphrase read to encrypt it
+		passphrase := newPassphraseFunc(c, false)
+		var newKeyPassphrase passphraseFunc
+		if hasPassphraseSource(c) {
+			passphrase = newPassphraseFunc(c, true)
+			newKeyPassphrase = passphrase
+		}
+
+		// load our signing privKey
+		privKey, err := getOrNewSigningCert(&signerKey, signerID, passphrase, newKeyPassphrase, policy)
+		if nil != err {
+			log.Fatalln(err)
+		}
+		signer = reseed.NewKeySigner(privKey, []byte(signerID))
 	}
 
 	// create a local file netdb provider
 	netdb := reseed.NewLocalNetDb(netdbDir)
+	netdb.MaxAge = c.Duration("maxRiAge")
 
 	// create a reseeder
 	reseeder := reseed.NewReseeder(netdb)
-	reseeder.SigningKey = privKey
-	reseeder.SignerID = []byte(signerID)
+	reseeder.Signer = signer
+	reseeder.Policy = reseed.SelectionPolicy{
+		ExcludeUnreachable: c.BoolT("excludeUnreachable"),
+		ExcludeHidden:      c.BoolT("excludeHidden"),
+		MinBandwidth:       minBandwidth,
+		MinVersion:         minVersion,
+		ExcludeUnversioned: c.Bool("excludeUnversioned"),
+		FloodfillShare:     c.Float64("floodfillShare"),
+		MaxPerSubnet:       c.Int("maxPerSubnet"),
+		StrictSubnets:      c.Bool("strictSubnets"),
+		BalanceAddresses:   c.BoolT("balanceAddresses"),
+	}
 	reseeder.NumRi = c.Int("numRi")
 	reseeder.NumSu3 = c.Int("numSu3")
 	reseeder.RebuildInterval = reloadIntvl
 	reseeder.Start()
 
+	if nil != rotating {
+		go watchIdentities(identitiesFile, passphrases, rotating, reseeder)
+	}
+
 	// create a server
 	server := reseed.NewServer(c.String("prefix"), c.Bool("trustProxy"))
 	server.Reseeder = reseeder
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/MDrollette/i2p-tools/su3"
+	"github.com/codegangsta/cli"
+)
+
+func NewSu3SignCommand() cli.Command {
+	return cli.Command{
+		Name:        "sign",
+		Usage:       "Sign a file into a Su3 file",
+		Description: "Wrap any file (news feed, plugin, router update, ...) into a signed Su3 file",
+		ArgsUsage:   "<input file>",
+		Action:      su3SignAction,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "signer",
+				Usage: "Your su3 signing ID (ex. something@mail.i2p)",
+			},
+			cli.StringFlag{
+				Name:  "key",
+				Usage: "Path to your su3 signing private key",
+			},
+			cli.StringFlag{
+				Name:  "contentType",
+				Usage: "Su3 content type (unknown, router, plugin, reseed, news, blocklist)",
+			},
+			cli.StringFlag{
+				Name:  "fileType",
+				Usage: "Su3 file type (zip, xml, html, xml.gz, txt.gz, dmg, exe). Guessed from the input file name if omitted",
+			},
+			cli.StringFlag{
+				Name:  "version",
+				Usage: "Version string stored in the su3 (default: current unix time)",
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Usage: "Path of the su3 file to write (default: <input file>.su3)",
+			},
+		}, passphraseFlags...),
+	}
+}
+
+func su3SignAction(c *cli.Context) error {
+	inFile := c.Args().Get(0)
+	if inFile == "" {
+		return cli.NewExitError("You must specify an input file", 2)
+	}
+
+	signerID := c.String("signer")
+	if signerID == "" {
+		return cli.NewExitError("--signer is required", 2)
+	}
+
+	su3File := su3.New()
+
+	contentType := c.String("contentType")
+	if contentType == "" {
+		return cli.NewExitError("--contentType is required", 2)
+	}
+	if err := su3File.ContentType.UnmarshalText([]byte(contentType)); nil != err {
+		return cli.NewExitError(err.Error(), 2)
+	}
+
+	fileType := c.String("fileType")
+	if fileType == "" {
+		if fileType = guessFileType(inFile); fileType == "" {
+			return cli.NewExitError("Unable to guess the file type, use --fileType", 2)
+		}
+	}
+	if err := su3File.FileType.UnmarshalText([]byte(fileType)); nil != err {
+		return cli.NewExitError(err.Error(), 2)
+	}
+
+	if version := c.String("version"); version != "" {
+		su3File.Version = []byte(version)
+	}
+
+	signerKey := c.String("key")
+	// if no key is specified, default to the signerID.pem in the current dir
+	if signerKey == "" {
+		signerKey = signerFile(signerID) + ".pem"
+	}
+
+	privKey, err := loadPrivateKey(signerKey, newPassphraseFunc(c, false))
+	if nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	su3File.SignatureType, err = su3.SignatureTypeForKey(privKey.Public())
+	if nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	su3File.Content, err = ioutil.ReadFile(inFile)
+	if nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	su3File.SignerID = []byte(signerID)
+	if err := su3File.Sign(privKey); nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	data, err := su3File.MarshalBinary()
+	if nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	outFile := c.String("out")
+	if outFile == "" {
+		outFile = inFile + ".su3"
+	}
+	if err := ioutil.WriteFile(outFile, data, 0644); nil != err {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	fmt.Println(su3File.String())
+	fmt.Printf("Signed su3 saved to: %s\n", outFile)
+	return nil
+}
+
+// guessFileType maps a file name extension to an su3 file type name.
+func guessFileType(name string) string {
+	name = strings.ToLower(name)
+	for _, ext := range []string{"xml.gz", "txt.gz", "zip", "xml", "html", "dmg", "exe"} {
+		if strings.HasSuffix(name, "."+ext) {
+			return ext
+		}
+	}
+	return ""
+}
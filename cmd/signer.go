@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/reseed"
+	"github.com/MDrollette/i2p-tools/su3"
+	"github.com/codegangsta/cli"
+)
+
+func NewSignerCommand() cli.Command {
+	return cli.Command{
+		Name:        "signer",
+		Usage:       "Run a signing agent that holds the su3 signing key",
+		Description: "Sign su3 files for a reseed server started with --signerSocket, so the internet facing process never holds the key",
+		Action:      signerAction,
+		Flags: append([]cli.Flag{
+			cli.StringFlag{
+				Name:  "signer",
+				Usage: "Your su3 signing ID (ex. something@mail.i2p)",
+			},
+			cli.StringFlag{
+				Name:  "key",
+				Usage: "Path to your su3 signing private key",
+			},
+			cli.StringFlag{
+				Name:  "socket",
+				Value: "signer.sock",
+				Usage: "Path of the unix socket to listen on",
+			},
+			cli.StringFlag{
+				Name:  "allow",
+				Value: "reseed",
+				Usage: "Comma separated list of su3 content types the agent will sign",
+			},
+			cli.IntFlag{
+				Name:  "maxPerHour",
+				Value: 1500,
+				Usage: "Maximum number of signatures per hour (0 = unlimited)",
+			},
+			cli.DurationFlag{
+				Name:  "maxVersionSkew",
+				Value: 10 * time.Minute,
+				Usage: "How far the version timestamp of a reseed su3 may be from the agent's clock",
+			},
+			cli.StringFlag{
+				Name:  "auditLog",
+				Usage: "Path to append the audit log to (default: stdout)",
+			},
+		}, passphraseFlags...),
+	}
+}
+
+func signerAction(c *cli.Context) {
+	signerID := c.String("signer")
+	if signerID == "" {
+		fmt.Println("--signer is required")
+		return
+	}
+
+	signerKey := c.String("key")
+	// if no key is specified, default to the signerID.pem in the current dir
+	if signerKey == "" {
+		signerKey = signerFile(signerID) + ".pem"
+	}
+
+	privKey, err := loadPrivateKey(signerKey, newPassphraseFunc(c, false))
+	if nil != err {
+		log.Fatalln(err)
+	}
+
+	agent := reseed.NewSigningAgent(reseed.NewKeySigner(privKey, []byte(signerID)))
+	agent.MaxPerHour = c.Int("maxPerHour")
+	agent.MaxVersionSkew = c.Duration("maxVersionSkew")
+
+	agent.ContentTypes = nil
+	for _, name := range strings.Split(c.String("allow"), ",") {
+		var contentType su3.ContentType
+		if err := contentType.UnmarshalText([]byte(strings.TrimSpace(name))); nil != err {
+			log.Fatalln(err)
+		}
+		agent.ContentTypes = append(agent.ContentTypes, contentType)
+	}
+
+	if auditFile := c.String("auditLog"); auditFile != "" {
+		f, err := os.OpenFile(auditFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if nil != err {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		agent.Audit = log.New(f, "", log.LstdFlags)
+	}
+
+	// remove a stale socket from a previous run
+	socket := c.String("socket")
+	os.Remove(socket)
+	ln, err := net.Listen("unix", socket)
+	if nil != err {
+		log.Fatalln(err)
+	}
+	// only the owner and group (ex. the reseed user) may connect
+	if err := os.Chmod(socket, 0660); nil != err {
+		log.Fatalln(err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		ln.Close()
+	}()
+
+	log.Printf("Signing agent for %s listening on %s\n", signerID, socket)
+	if err := agent.Serve(ln); nil != err && !errors.Is(err, net.ErrClosed) {
+		log.Println(err)
+	}
+	os.Remove(socket)
+}
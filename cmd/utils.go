@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"bufio"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -10,74 +12,215 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/MDrollette/i2p-tools/reseed"
 	"github.com/MDrollette/i2p-tools/su3"
+	"github.com/codegangsta/cli"
 )
 
-func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+// loadPrivateKey reads the first private key from a PEM file. PKCS#1, SEC 1
+// and PKCS#8 keys are supported, encrypted ones ask passphrase for the
+// passphrase.
+func loadPrivateKey(path string, passphrase passphraseFunc) (crypto.Signer, error) {
 	privPem, err := ioutil.ReadFile(path)
 	if nil != err {
 		return nil, err
 	}
 
-	privDer, _ := pem.Decode(privPem)
-	privKey, err := x509.ParsePKCS1PrivateKey(privDer.Bytes)
+	for block, rest := pem.Decode(privPem); nil != block; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
+			privKey, err := parsePrivateKeyBlock(block, passphrase)
+			if nil != err {
+				return nil, fmt.Errorf("%s: %s", path, err)
+			}
+			return privKey, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no private key found in %s", path)
+}
+
+func parsePrivateKeyBlock(block *pem.Block, passphrase passphraseFunc) (crypto.Signer, error) {
+	der := block.Bytes
+
+	encrypted := "ENCRYPTED PRIVATE KEY" == block.Type || x509.IsEncryptedPEMBlock(block)
+	var pass []byte
+	if encrypted {
+		if nil == passphrase {
+			return nil, fmt.Errorf("key is encrypted and no passphrase is available")
+		}
+		var err error
+		if pass, err = passphrase(); nil != err {
+			return nil, err
+		}
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	switch {
+	case "ENCRYPTED PRIVATE KEY" == block.Type:
+		key, err = decryptPKCS8PrivateKey(der, pass)
+	case encrypted:
+		// legacy OpenSSL "Proc-Type: 4,ENCRYPTED" PEM
+		if der, err = x509.DecryptPEMBlock(block, pass); nil != err {
+			return nil, err
+		}
+		fallthrough
+	default:
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(der)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(der)
+		default:
+			key, err = x509.ParsePKCS8PrivateKey(der)
+		}
+	}
 	if nil != err {
 		return nil, err
 	}
 
-	return privKey, nil
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return signer, nil
 }
 
 func signerFile(signerID string) string {
 	return strings.Replace(signerID, "@", "_at_", 1)
 }
 
-func getOrNewSigningCert(signerKey *string, signerID string) (*rsa.PrivateKey, error) {
+// missingPolicy decides what reseed does when a key or certificate it needs
+// does not exist yet.
+type missingPolicy int
+
+const (
+	// promptMissing asks on an interactive terminal and fails otherwise.
+	promptMissing missingPolicy = iota
+	// failMissing never asks and fails.
+	failMissing
+	// generateMissing never asks and creates what is missing.
+	generateMissing
+)
+
+func newMissingPolicy(c *cli.Context) (missingPolicy, error) {
+	noPrompt := c.Bool("noPrompt")
+	generate := c.Bool("generateMissing")
+	switch {
+	case noPrompt && generate:
+		return 0, errors.New("--noPrompt and --generateMissing can not be combined")
+	case noPrompt:
+		return failMissing, nil
+	case generate:
+		return generateMissing, nil
+	}
+	return promptMissing, nil
+}
+
+// generate reports whether a missing item should be created. Every decision
+// that is not answered on a terminal is logged.
+func (p missingPolicy) generate(what, question string) bool {
+	switch p {
+	case generateMissing:
+		log.Printf("%s, generating a new one (--generateMissing)\n", what)
+		return true
+	case failMissing:
+		log.Printf("%s, not generating one (--noPrompt)\n", what)
+		return false
+	}
+
+	if !isTerminal(os.Stdin) {
+		log.Printf("%s, not generating one (stdin is not a terminal)\n", what)
+		return false
+	}
+
+	fmt.Println(what)
+	return askYesNo(question)
+}
+
+// isTerminal reports whether f is a character device other than /dev/null,
+// i.e. whether someone could be there to answer.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if nil != err || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	if null, err := os.Stat(os.DevNull); nil == err && os.SameFile(fi, null) {
+		return false
+	}
+	return true
+}
+
+// askYesNo prompts on stdin. Anything but an answer starting with y,
+// including EOF, is a no.
+func askYesNo(question string) bool {
+	fmt.Printf("%s (y or n): ", question)
+	input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	input = strings.TrimSpace(input)
+	return input != "" && (input[0] == 'y' || input[0] == 'Y')
+}
+
+// getOrNewSigningCert loads signerKey with passphrase, generating it first if
+// it is missing. A generated key is encrypted with newKeyPassphrase unless
+// that is nil.
+func getOrNewSigningCert(signerKey *string, signerID string, passphrase, newKeyPassphrase passphraseFunc, policy missingPolicy) (crypto.Signer, error) {
 	if _, err := os.Stat(*signerKey); nil != err {
-		fmt.Printf("Unable to read signing key '%s'\n", *signerKey)
-		fmt.Printf("Would you like to generate a new signing key for %s? (y or n): ", signerID)
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		if []byte(input)[0] != 'y' {
-			return nil, fmt.Errorf("A signing key is required")
-		}
-		if err := createSigningCertificate(signerID); nil != err {
+		what := fmt.Sprintf("Unable to read signing key '%s'", *signerKey)
+		question := fmt.Sprintf("Would you like to generate a new signing key for %s?", signerID)
+		if !policy.generate(what, question) {
+			return nil, fmt.Errorf("A signing key is required (use --generateMissing to create one)")
+		}
+		if err := createSigningCertificate(signerID, defaultSignerOptions(), newKeyPassphrase); nil != err {
 			return nil, err
 		}
 
 		*signerKey = signerFile(signerID) + ".pem"
 	}
 
-	return loadPrivateKey(*signerKey)
+	return loadPrivateKey(*signerKey, passphrase)
 }
 
-func checkOrNewTLSCert(tlsHost string, tlsCert, tlsKey *string) error {
+// checkOrNewTLSCert makes sure tlsCert and tlsKey exist. When the user
+// declines to create them on a terminal both are cleared and reseed continues
+// without TLS.
+func checkOrNewTLSCert(tlsHost string, tlsCert, tlsKey *string, policy missingPolicy) error {
 	_, certErr := os.Stat(*tlsCert)
 	_, keyErr := os.Stat(*tlsKey)
 	if certErr != nil || keyErr != nil {
+		var missing []string
 		if certErr != nil {
-			fmt.Printf("Unable to read TLS certificate '%s'\n", *tlsCert)
+			missing = append(missing, fmt.Sprintf("TLS certificate '%s'", *tlsCert))
 		}
 		if keyErr != nil {
-			fmt.Printf("Unable to read TLS key '%s'\n", *tlsKey)
+			missing = append(missing, fmt.Sprintf("TLS key '%s'", *tlsKey))
 		}
 
-		fmt.Printf("Would you like to generate a new self-signed certificate for '%s'? (y or n): ", tlsHost)
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		if []byte(input)[0] != 'y' {
+		what := "Unable to read " + strings.Join(missing, " and ")
+		question := fmt.Sprintf("Would you like to generate a new self-signed certificate for '%s'?", tlsHost)
+		if !policy.generate(what, question) {
+			if policy != promptMissing || !isTerminal(os.Stdin) {
+				return fmt.Errorf("A TLS certificate and key are required for %s (use --generateMissing to create them)", tlsHost)
+			}
 			fmt.Println("Continuing without TLS")
+			*tlsCert, *tlsKey = "", ""
 			return nil
 		}
 
-		if err := createTLSCertificate(tlsHost); nil != err {
+		if err := createTLSCertificate(tlsHost, defaultTLSOptions()); nil != err {
 			return err
 		}
 
@@ -88,130 +231,297 @@ func checkOrNewTLSCert(tlsHost string, tlsCert, tlsKey *string) error {
 	return nil
 }
 
-func createSigningCertificate(signerID string) error {
+// certOptions controls the keys and certificates created by keygen. Empty
+// fields keep the defaults of the certificate templates.
+type certOptions struct {
+	Algo     string
+	Validity string
+	OutDir   string
+	Subject  pkix.Name
+	Force    bool
+}
+
+func defaultSignerOptions() certOptions {
+	return certOptions{Algo: "RSA-4096"}
+}
+
+func defaultTLSOptions() certOptions {
+	return certOptions{Algo: "ECDSA-P384"}
+}
+
+// applyTo overrides the validity and subject of template. The common name
+// always stays the signer ID or host.
+func (o certOptions) applyTo(template *x509.Certificate) error {
+	if o.Validity != "" {
+		notAfter, err := parseValidity(o.Validity, template.NotBefore)
+		if nil != err {
+			return err
+		}
+		template.NotAfter = notAfter
+	}
+
+	subject := &template.Subject
+	if 0 != len(o.Subject.Organization) {
+		subject.Organization = o.Subject.Organization
+	}
+	if 0 != len(o.Subject.OrganizationalUnit) {
+		subject.OrganizationalUnit = o.Subject.OrganizationalUnit
+	}
+	if 0 != len(o.Subject.Country) {
+		subject.Country = o.Subject.Country
+	}
+	if 0 != len(o.Subject.Province) {
+		subject.Province = o.Subject.Province
+	}
+	if 0 != len(o.Subject.Locality) {
+		subject.Locality = o.Subject.Locality
+	}
+	if 0 != len(o.Subject.StreetAddress) {
+		subject.StreetAddress = o.Subject.StreetAddress
+	}
+
+	return nil
+}
+
+// parseValidity turns a validity like "10y", "90d" or "2160h" into the end
+// of a validity period starting at from.
+func parseValidity(validity string, from time.Time) (time.Time, error) {
+	if n, err := strconv.Atoi(strings.TrimRight(validity, "yd")); nil == err && n > 0 {
+		switch {
+		case strings.HasSuffix(validity, "y"):
+			return from.AddDate(n, 0, 0), nil
+		case strings.HasSuffix(validity, "d"):
+			return from.AddDate(0, 0, n), nil
+		}
+	}
+
+	d, err := time.ParseDuration(validity)
+	if nil != err || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid validity period: %s (ex. 10y, 90d, 2160h)", validity)
+	}
+	return from.Add(d), nil
+}
+
+func newPrivateKey(algo string) (crypto.Signer, error) {
+	switch strings.ToUpper(algo) {
+	case "RSA-2048":
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case "RSA-3072":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case "RSA-4096":
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case "ECDSA-P256":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ECDSA-P384":
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case "ECDSA-P521":
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case "ED25519":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, fmt.Errorf("unknown key algorithm: %s", algo)
+	}
+}
+
+// curveOIDs are the named curves from http://www.ietf.org/rfc/rfc5480.txt
+var curveOIDs = map[elliptic.Curve]asn1.ObjectIdentifier{
+	elliptic.P256(): {1, 2, 840, 10045, 3, 1, 7},
+	elliptic.P384(): {1, 3, 132, 0, 34},
+	elliptic.P521(): {1, 3, 132, 0, 35},
+}
+
+// privateKeyPem encodes key for saving. With a passphrase the key is written
+// as encrypted PKCS#8.
+func privateKeyPem(key crypto.Signer, passphrase passphraseFunc) ([]*pem.Block, error) {
+	if nil != passphrase {
+		pass, err := passphrase()
+		if nil != err {
+			return nil, err
+		}
+		der, err := encryptPKCS8PrivateKey(key, pass)
+		if nil != err {
+			return nil, err
+		}
+		return []*pem.Block{{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}}, nil
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return []*pem.Block{{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}}, nil
+	case *ecdsa.PrivateKey:
+		params, err := asn1.Marshal(curveOIDs[k.Curve])
+		if nil != err {
+			return nil, err
+		}
+		ecder, err := x509.MarshalECPrivateKey(k)
+		if nil != err {
+			return nil, err
+		}
+		return []*pem.Block{{Type: "EC PARAMETERS", Bytes: params}, {Type: "EC PRIVATE KEY", Bytes: ecder}}, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if nil != err {
+		return nil, err
+	}
+	return []*pem.Block{{Type: "PRIVATE KEY", Bytes: der}}, nil
+}
+
+// checkOverwrite fails if any of the files keygen writes for base exists.
+func checkOverwrite(base string, force bool) error {
+	if force {
+		return nil
+	}
+	for _, ext := range []string{".crt", ".pem", ".crl", ".revocation.pem"} {
+		if _, err := os.Stat(base + ext); nil == err {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", base+ext)
+		}
+	}
+	return nil
+}
+
+func createSigningCertificate(signerID string, opts certOptions, passphrase passphraseFunc) error {
+	base := filepath.Join(opts.OutDir, signerFile(signerID))
+	if err := checkOverwrite(base, opts.Force); nil != err {
+		return err
+	}
+
 	// generate private key
 	fmt.Println("Generating signing keys. This may take a minute...")
-	signerKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	signerKey, err := newPrivateKey(opts.Algo)
 	if err != nil {
 		return err
 	}
+	if _, err := su3.SignatureTypeForKey(signerKey.Public()); nil != err {
+		return err
+	}
 
-	signerCert, err := su3.NewSigningCertificate(signerID, signerKey)
+	template, err := su3.SigningCertificateTemplate(signerID)
 	if nil != err {
 		return err
 	}
-
-	// save cert
-	certFile := signerFile(signerID) + ".crt"
-	certOut, err := os.Create(certFile)
-	if err != nil {
-		return fmt.Errorf("failed to open %s for writing: %v", certFile, err)
+	if err := opts.applyTo(template); nil != err {
+		return err
 	}
-	pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: signerCert})
-	certOut.Close()
-	fmt.Println("\tSigning certificate saved to:", certFile)
 
-	// save signing private key
-	privFile := signerFile(signerID) + ".pem"
-	keyOut, err := os.OpenFile(privFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return fmt.Errorf("failed to open %s for writing: %v", privFile, err)
+	signerCert, err := x509.CreateCertificate(rand.Reader, template, template, signerKey.Public(), signerKey)
+	if nil != err {
+		return err
 	}
-	pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(signerKey)})
-	pem.Encode(keyOut, &pem.Block{Type: "CERTIFICATE", Bytes: signerCert})
-	keyOut.Close()
-	fmt.Println("\tSigning private key saved to:", privFile)
 
-	// CRL
-	crlFile := signerFile(signerID) + ".crl"
-	crlOut, err := os.OpenFile(crlFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	keyPem, err := privateKeyPem(signerKey, passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to open %s for writing: %s", crlFile, err)
+		return err
 	}
-	crlcert, err := x509.ParseCertificate(signerCert)
-	if err != nil {
-		return fmt.Errorf("Certificate with unknown critical extension was not parsed: %s", err)
+
+	return saveCertificate(base, "Signing", signerCert, signerKey, keyPem)
+}
+
+func createTLSCertificate(host string, opts certOptions) error {
+	base := filepath.Join(opts.OutDir, host)
+	if err := checkOverwrite(base, opts.Force); nil != err {
+		return err
 	}
 
-	now := time.Now()
-	revokedCerts := []pkix.RevokedCertificate{
-		{
-			SerialNumber:   crlcert.SerialNumber,
-			RevocationTime: now,
-		},
+	if strings.EqualFold(opts.Algo, "Ed25519") {
+		return fmt.Errorf("Ed25519 is not supported for TLS certificates, use RSA or ECDSA")
 	}
 
-	crlBytes, err := crlcert.CreateCRL(rand.Reader, signerKey, revokedCerts, now, now)
+	fmt.Println("Generating TLS keys. This may take a minute...")
+	priv, err := newPrivateKey(opts.Algo)
 	if err != nil {
-		return fmt.Errorf("error creating CRL: %s", err)
+		return err
 	}
-	_, err = x509.ParseDERCRL(crlBytes)
-	if err != nil {
-		return fmt.Errorf("error reparsing CRL: %s", err)
+
+	template, err := reseed.TLSCertificateTemplate(host)
+	if nil != err {
+		return err
+	}
+	if err := opts.applyTo(template); nil != err {
+		return err
+	}
+	// the template asks for ECDSA with SHA512, let RSA keys pick their default
+	if _, ok := priv.(*ecdsa.PrivateKey); !ok {
+		template.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
 	}
-	pem.Encode(crlOut, &pem.Block{Type: "X509 CRL", Bytes: crlBytes})
-	crlOut.Close()
-	fmt.Printf("\tSigning CRL saved to: %s\n", crlFile)
 
-	return nil
-}
+	tlsCert, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if nil != err {
+		return err
+	}
 
-func createTLSCertificate(host string) error {
-	fmt.Println("Generating TLS keys. This may take a minute...")
-	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	keyPem, err := privateKeyPem(priv, nil)
 	if err != nil {
 		return err
 	}
 
-	tlsCert, err := reseed.NewTLSCertificate(host, priv)
-	if nil != err {
+	return saveCertificate(base, "TLS", tlsCert, priv, keyPem)
+}
+
+// saveCertificate writes base.crt, base.pem (the key followed by the
+// certificate), base.crl, an empty CRL, and base.revocation.pem, a CRL
+// revoking the certificate to publish in case the key is ever compromised.
+func saveCertificate(base, label string, cert []byte, key crypto.Signer, keyPem []*pem.Block) error {
+	if err := os.MkdirAll(filepath.Dir(base), 0755); nil != err {
 		return err
 	}
 
-	// save the TLS certificate
-	certOut, err := os.Create(host + ".crt")
+	// save cert
+	certFile := base + ".crt"
+	certOut, err := os.Create(certFile)
 	if err != nil {
-		return fmt.Errorf("failed to open %s for writing: %s", host+".crt", err)
+		return fmt.Errorf("failed to open %s for writing: %v", certFile, err)
 	}
-	pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: tlsCert})
+	pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: cert})
 	certOut.Close()
-	fmt.Printf("\tTLS certificate saved to: %s\n", host+".crt")
+	fmt.Printf("\t%s certificate saved to: %s\n", label, certFile)
 
-	// save the TLS private key
-	privFile := host + ".pem"
+	// save private key
+	privFile := base + ".pem"
 	keyOut, err := os.OpenFile(privFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to open %s for writing: %v", privFile, err)
 	}
-	secp384r1, err := asn1.Marshal(asn1.ObjectIdentifier{1, 3, 132, 0, 34}) // http://www.ietf.org/rfc/rfc5480.txt
-	pem.Encode(keyOut, &pem.Block{Type: "EC PARAMETERS", Bytes: secp384r1})
-	ecder, err := x509.MarshalECPrivateKey(priv)
-	pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecder})
-	pem.Encode(keyOut, &pem.Block{Type: "CERTIFICATE", Bytes: tlsCert})
-
+	for _, block := range keyPem {
+		pem.Encode(keyOut, block)
+	}
+	pem.Encode(keyOut, &pem.Block{Type: "CERTIFICATE", Bytes: cert})
 	keyOut.Close()
-	fmt.Printf("\tTLS private key saved to: %s\n", privFile)
+	fmt.Printf("\t%s private key saved to: %s\n", label, privFile)
 
-	// CRL
-	crlFile := host + ".crl"
-	crlOut, err := os.OpenFile(crlFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return fmt.Errorf("failed to open %s for writing: %s", crlFile, err)
-	}
-	crlcert, err := x509.ParseCertificate(tlsCert)
+	crlcert, err := x509.ParseCertificate(cert)
 	if err != nil {
 		return fmt.Errorf("Certificate with unknown critical extension was not parsed: %s", err)
 	}
 
+	// empty CRL to publish next to the certificate
 	now := time.Now()
+	crlFile := base + ".crl"
+	if err := writeCRL(crlFile, crlcert, key, nil, now); nil != err {
+		return err
+	}
+	fmt.Printf("\t%s CRL saved to: %s\n", label, crlFile)
+
+	// CRL revoking the certificate, kept aside until the key is compromised
+	revocationFile := base + ".revocation.pem"
 	revokedCerts := []pkix.RevokedCertificate{
 		{
 			SerialNumber:   crlcert.SerialNumber,
 			RevocationTime: now,
 		},
 	}
+	if err := writeCRL(revocationFile, crlcert, key, revokedCerts, now); nil != err {
+		return err
+	}
+	fmt.Printf("\t%s revocation CRL saved to: %s (publish it as %s if the key is compromised)\n", label, revocationFile, filepath.Base(crlFile))
+
+	return nil
+}
 
-	crlBytes, err := crlcert.CreateCRL(rand.Reader, priv, revokedCerts, now, now)
+// writeCRL writes a PEM CRL issued by cert listing revokedCerts.
+func writeCRL(crlFile string, cert *x509.Certificate, key crypto.Signer, revokedCerts []pkix.RevokedCertificate, now time.Time) error {
+	crlBytes, err := cert.CreateCRL(rand.Reader, key, revokedCerts, now, now)
 	if err != nil {
 		return fmt.Errorf("error creating CRL: %s", err)
 	}
@@ -219,9 +529,11 @@ func createTLSCertificate(host string) error {
 	if err != nil {
 		return fmt.Errorf("error reparsing CRL: %s", err)
 	}
-	pem.Encode(crlOut, &pem.Block{Type: "X509 CRL", Bytes: crlBytes})
-	crlOut.Close()
-	fmt.Printf("\tTLS CRL saved to: %s\n", crlFile)
 
-	return nil
+	crlOut, err := os.OpenFile(crlFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for writing: %s", crlFile, err)
+	}
+	defer crlOut.Close()
+	return pem.Encode(crlOut, &pem.Block{Type: "X509 CRL", Bytes: crlBytes})
 }
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MDrollette/i2p-tools/su3"
+)
+
+// TestKeygenKeysSign checks that the keys keygen writes load and sign su3
+// files that verify against their certificate.
+func TestKeygenKeysSign(t *testing.T) {
+	pass := func() ([]byte, error) { return []byte("secret"), nil }
+
+	for _, algo := range []string{"RSA-2048", "ECDSA-P256", "ECDSA-P384", "ECDSA-P521", "Ed25519"} {
+		for _, passphrase := range []passphraseFunc{nil, pass} {
+			key, err := newPrivateKey(algo)
+			if nil != err {
+				t.Fatal(err)
+			}
+			blocks, err := privateKeyPem(key, passphrase)
+			if nil != err {
+				t.Fatalf("%s: %s", algo, err)
+			}
+
+			keyFile := filepath.Join(t.TempDir(), "key.pem")
+			f, err := os.Create(keyFile)
+			if nil != err {
+				t.Fatal(err)
+			}
+			for _, block := range blocks {
+				pem.Encode(f, block)
+			}
+			f.Close()
+
+			loaded, err := loadPrivateKey(keyFile, passphrase)
+			if nil != err {
+				t.Fatalf("%s: %s", algo, err)
+			}
+
+			sigType, err := su3.SignatureTypeForKey(loaded.Public())
+			if nil != err {
+				t.Fatalf("%s: %s", algo, err)
+			}
+			su3File := su3.New()
+			su3File.SignatureType = sigType
+			su3File.SignerID = []byte("test@mail.i2p")
+			su3File.Content = []byte("content")
+			if err := su3File.Sign(loaded); nil != err {
+				t.Fatalf("%s: %s", algo, err)
+			}
+
+			der, err := su3.NewSigningCertificate("test@mail.i2p", key)
+			if nil != err {
+				t.Fatal(err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if err := su3File.VerifySignature(cert); nil != err {
+				t.Errorf("%s: %s", algo, err)
+			}
+		}
+	}
+}
@@ -1,8 +1,18 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/MDrollette/i2p-tools/reseed"
 	"github.com/MDrollette/i2p-tools/su3"
@@ -12,47 +22,286 @@ import (
 func NewSu3VerifyCommand() cli.Command {
 	return cli.Command{
 		Name:        "verify",
-		Usage:       "Verify a Su3 file",
-		Description: "Verify a Su3 file",
+		Usage:       "Verify Su3 files",
+		Description: "Verify one or more Su3 files. Arguments can be files, globs or directories. Exits non-zero if any file fails verification.",
+		ArgsUsage:   "<file|glob|directory>...",
 		Action:      su3VerifyAction,
 		Flags: []cli.Flag{
 			cli.BoolFlag{
 				Name:  "extract",
-				Usage: "Also extract the contents of the su3",
+				Usage: "Also extract the contents of the su3 (not with --json)",
+			},
+			cli.StringFlag{
+				Name:  "out",
+				Usage: "Destination for --extract, a directory for zip content or a file otherwise",
+			},
+			cli.StringFlag{
+				Name:  "certs",
+				Value: "./certificates",
+				Usage: "Path to the certificates directory (with reseed/, news/, plugin/ and router/ subdirectories)",
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the header and verification result as JSON",
+			},
+			cli.IntFlag{
+				Name:  "workers",
+				Value: 0,
+				Usage: "Number of files to verify concurrently (0 = number of CPUs)",
+			},
+			cli.DurationFlag{
+				Name:  "maxAge",
+				Value: 0,
+				Usage: "Fail files whose version timestamp is older than this (ex. 72h, 0 = no limit). Files dated in the future always fail",
 			},
 		},
 	}
 }
 
-func su3VerifyAction(c *cli.Context) {
-	su3File := su3.New()
+// su3Report is the structured form of a verification printed by --json.
+type su3Report struct {
+	File          string          `json:"file"`
+	Format        uint8           `json:"format"`
+	SignatureType uint16          `json:"signatureType"`
+	FileType      su3.FileType    `json:"fileType"`
+	ContentType   su3.ContentType `json:"contentType"`
+	Version       string          `json:"version"`
+	SignerID      string          `json:"signerId"`
+	ContentLength int             `json:"contentLength"`
+	Timestamp     *time.Time      `json:"timestamp,omitempty"`
+	Age           string          `json:"age,omitempty"`
+	Valid         bool            `json:"valid"`
+	Error         string          `json:"error,omitempty"`
+}
 
-	data, err := ioutil.ReadFile(c.Args().Get(0))
+func newSu3Report(path string, su3File *su3.File) *su3Report {
+	report := &su3Report{
+		File:          path,
+		Format:        su3File.Format,
+		SignatureType: su3File.SignatureType,
+		FileType:      su3File.FileType,
+		ContentType:   su3File.ContentType,
+		Version:       string(bytes.Trim(su3File.Version, "\x00")),
+		SignerID:      string(su3File.SignerID),
+		ContentLength: len(su3File.Content),
+	}
+	if ts, err := su3File.Timestamp(); nil == err {
+		report.Timestamp = &ts
+		report.Age = su3Age(ts, time.Now())
+	}
+	return report
+}
+
+func su3VerifyAction(c *cli.Context) error {
+	paths, err := expandSu3Paths(c.Args())
 	if nil != err {
-		panic(err)
+		return cli.NewExitError(err.Error(), 2)
+	}
+	if 0 == len(paths) {
+		return cli.NewExitError("You must specify at least one su3 file, glob or directory", 2)
+	}
+	if c.Bool("extract") && len(paths) > 1 {
+		return cli.NewExitError("--extract only works with a single su3 file", 2)
+	}
+	if c.Bool("extract") && c.Bool("json") {
+		return cli.NewExitError("--extract can't be combined with --json", 2)
+	}
+
+	ks := &reseed.KeyStore{Path: c.String("certs")}
+	maxAge := c.Duration("maxAge")
+
+	// a single file gets the detailed output and can be extracted
+	if 1 == len(paths) && !c.Bool("json") {
+		su3File := new(su3.File)
+		err := verifySu3(paths[0], su3File, ks, maxAge)
+		fmt.Println(su3File.String())
+		if ts, tsErr := su3File.Timestamp(); nil == tsErr {
+			fmt.Printf("Timestamp: %s (age %s)\n", ts.UTC().Format(time.RFC3339), su3Age(ts, time.Now()))
+		}
+		if nil != err {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Printf("Signature is valid for signer '%s'\n", su3File.SignerID)
+
+		if c.Bool("extract") {
+			out, err := extractSu3(su3File, c.String("out"))
+			if nil != err {
+				return cli.NewExitError(err.Error(), 1)
+			}
+			fmt.Printf("Content extracted to: %s\n", out)
+		}
+		return nil
+	}
+
+	reports := verifyAll(paths, ks, maxAge, c.Int("workers"))
+
+	failed := 0
+	for _, report := range reports {
+		if !report.Valid {
+			failed++
+		}
+	}
+
+	if c.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(reports)
+	} else {
+		printSu3Reports(os.Stdout, reports)
+		fmt.Printf("\n%d files checked, %d valid, %d failed\n", len(reports), len(reports)-failed, failed)
+	}
+
+	if failed > 0 {
+		return cli.NewExitError("", 1)
+	}
+	return nil
+}
+
+// expandSu3Paths turns arguments into a list of files. Globs are expanded
+// and directories are searched recursively for .su3 files.
+func expandSu3Paths(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if nil != err {
+			return nil, err
+		}
+		// keep arguments that don't exist so they get reported as failures
+		if 0 == len(matches) {
+			matches = []string{arg}
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if nil != err || !info.IsDir() {
+				paths = append(paths, match)
+				continue
+			}
+
+			err = filepath.Walk(match, func(path string, f os.FileInfo, err error) error {
+				if nil == err && !f.IsDir() && strings.EqualFold(filepath.Ext(path), ".su3") {
+					paths = append(paths, path)
+				}
+				return err
+			})
+			if nil != err {
+				return nil, err
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// verifyAll checks paths concurrently and returns the reports in the same
+// order.
+func verifyAll(paths []string, ks *reseed.KeyStore, maxAge time.Duration, workers int) []*su3Report {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	reports := make([]*su3Report, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				su3File := new(su3.File)
+				err := verifySu3(paths[i], su3File, ks, maxAge)
+
+				report := newSu3Report(paths[i], su3File)
+				report.Valid = nil == err
+				if nil != err {
+					report.Error = err.Error()
+				}
+				reports[i] = report
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return reports
+}
+
+func printSu3Reports(w io.Writer, reports []*su3Report) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSTATUS\tSIGNER\tCONTENT\tVERSION\tAGE\tERROR")
+	for _, r := range reports {
+		status := "OK"
+		if !r.Valid {
+			status = "FAIL"
+		}
+		age := r.Age
+		if age == "" {
+			age = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.File, status, r.SignerID, r.ContentType, r.Version, age, r.Error)
+	}
+	tw.Flush()
+}
+
+// verifySu3 reads the file at path into su3File and checks its header,
+// signature against the matching certificate in ks and freshness.
+func verifySu3(path string, su3File *su3.File, ks *reseed.KeyStore, maxAge time.Duration) error {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return err
 	}
 	if err := su3File.UnmarshalBinary(data); err != nil {
-		panic(err)
+		return err
 	}
 
-	fmt.Println(su3File.String())
+	if err := su3File.Validate(); nil != err {
+		return err
+	}
 
-	// get the reseeder key
-	ks := reseed.KeyStore{Path: "./certificates"}
-	cert, err := ks.ReseederCertificate(su3File.SignerID)
+	// get the signer key
+	cert, err := ks.SignerCertificate(su3File.ContentType, su3File.SignerID)
 	if nil != err {
-		fmt.Println(err)
-		return
+		return err
 	}
 
 	if err := su3File.VerifySignature(cert); nil != err {
-		panic(err)
+		return err
 	}
 
-	fmt.Printf("Signature is valid for signer '%s'\n", su3File.SignerID)
+	return checkSu3Freshness(su3File, maxAge, time.Now())
+}
+
+// maxClockSkew is how far in the future a version timestamp may be before
+// the file is considered misdated.
+const maxClockSkew = 10 * time.Minute
+
+// checkSu3Freshness fails files dated in the future and, with a maxAge,
+// files older than that. Reseed files must carry a timestamp version, other
+// content types are only checked when they do.
+func checkSu3Freshness(su3File *su3.File, maxAge time.Duration, now time.Time) error {
+	ts, err := su3File.Timestamp()
+	if nil != err {
+		if su3File.ContentType == su3.ContentTypeReseed {
+			return err
+		}
+		return nil
+	}
 
-	if c.Bool("extract") {
-		// @todo: don't assume zip
-		ioutil.WriteFile("extracted.zip", su3File.BodyBytes(), 0755)
+	if ts.After(now.Add(maxClockSkew)) {
+		return fmt.Errorf("su3 file is dated %s in the future", ts.Sub(now).Round(time.Second))
 	}
+	if maxAge > 0 && now.Sub(ts) > maxAge {
+		return fmt.Errorf("su3 file is %s old, more than the maximum of %s", su3Age(ts, now), maxAge)
+	}
+	return nil
+}
+
+// su3Age formats the time since ts, negative for files dated in the future.
+func su3Age(ts, now time.Time) string {
+	return now.Sub(ts).Round(time.Second).String()
 }
@@ -22,7 +22,10 @@ func main() {
 	app.Commands = []cli.Command{
 		cmd.NewReseedCommand(),
 		cmd.NewSu3VerifyCommand(),
+		cmd.NewSu3SignCommand(),
+		cmd.NewSu3InspectCommand(),
 		cmd.NewKeygenCommand(),
+		cmd.NewSignerCommand(),
 		// cmd.NewSu3VerifyPublicCommand(),
 	}
 
@@ -0,0 +1,213 @@
+package reseed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/su3"
+)
+
+// SignRequest carries the fields of an unsigned su3 file to a SigningAgent.
+// The agent builds the signed bytes itself, so the policy checks apply to
+// exactly what gets signed.
+type SignRequest struct {
+	Format      uint8
+	FileType    su3.FileType
+	ContentType su3.ContentType
+	Version     []byte
+	Content     []byte
+}
+
+// SignResponse carries what the agent filled in.
+type SignResponse struct {
+	SignatureType uint16
+	SignerID      []byte
+	Signature     []byte
+}
+
+// SigningAgent holds the signing key in a separate process and signs su3
+// files for a reseed server over a unix socket.
+type SigningAgent struct {
+	signer *KeySigner
+
+	// ContentTypes that may be signed
+	ContentTypes []su3.ContentType
+	// MaxPerHour caps the number of signatures, 0 means no limit
+	MaxPerHour int
+	// MaxVersionSkew is how far the version timestamp of a reseed su3 may
+	// be from the agent's clock
+	MaxVersionSkew time.Duration
+	// Audit receives one line per signature or rejected request
+	Audit *log.Logger
+
+	m           sync.Mutex
+	windowStart time.Time
+	windowCount int
+}
+
+func NewSigningAgent(signer *KeySigner) *SigningAgent {
+	return &SigningAgent{
+		signer:         signer,
+		ContentTypes:   []su3.ContentType{su3.ContentTypeReseed},
+		MaxPerHour:     1500, // a few rebuilds of up to 300 su3 files
+		MaxVersionSkew: 10 * time.Minute,
+		Audit:          log.New(os.Stdout, "", log.LstdFlags),
+	}
+}
+
+// agentRPC exposes only the Sign method of a SigningAgent over net/rpc.
+type agentRPC struct {
+	agent *SigningAgent
+}
+
+func (a *agentRPC) Sign(req SignRequest, resp *SignResponse) error {
+	return a.agent.sign(&su3.File{
+		Format:      req.Format,
+		FileType:    req.FileType,
+		ContentType: req.ContentType,
+		Version:     req.Version,
+		Content:     req.Content,
+	}, resp)
+}
+
+// Serve accepts signing requests on ln until it is closed.
+func (a *SigningAgent) Serve(ln net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("SigningAgent", &agentRPC{agent: a}); nil != err {
+		return err
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if nil != err {
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+func (a *SigningAgent) sign(su3File *su3.File, resp *SignResponse) error {
+	if err := a.checkPolicy(su3File); nil != err {
+		a.Audit.Printf("REJECTED content=%s file=%s version=%q: %s", su3File.ContentType, su3File.FileType, bytes.Trim(su3File.Version, "\x00"), err)
+		return err
+	}
+
+	if err := a.signer.SignSu3(su3File); nil != err {
+		a.Audit.Printf("FAILED content=%s file=%s version=%q: %s", su3File.ContentType, su3File.FileType, bytes.Trim(su3File.Version, "\x00"), err)
+		return err
+	}
+
+	a.Audit.Printf("SIGNED signer=%s sigtype=%d content=%s file=%s version=%q length=%d sha256=%x",
+		su3File.SignerID, su3File.SignatureType, su3File.ContentType, su3File.FileType,
+		bytes.Trim(su3File.Version, "\x00"), len(su3File.Content), sha256.Sum256(su3File.Content))
+
+	resp.SignatureType = su3File.SignatureType
+	resp.SignerID = su3File.SignerID
+	resp.Signature = su3File.Signature
+
+	return nil
+}
+
+func (a *SigningAgent) checkPolicy(su3File *su3.File) error {
+	allowed := false
+	for _, t := range a.ContentTypes {
+		if t == su3File.ContentType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("content type %s is not allowed", su3File.ContentType)
+	}
+
+	if su3File.ContentType == su3.ContentTypeReseed {
+		if err := a.checkReseed(su3File); nil != err {
+			return err
+		}
+	}
+
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if 0 == a.MaxPerHour {
+		return nil
+	}
+	if time.Since(a.windowStart) > time.Hour {
+		a.windowStart = time.Now()
+		a.windowCount = 0
+	}
+	if a.windowCount >= a.MaxPerHour {
+		return fmt.Errorf("rate limit of %d signatures per hour reached", a.MaxPerHour)
+	}
+	a.windowCount++
+
+	return nil
+}
+
+// checkReseed makes sure a reseed request is a zip in the only su3 format
+// and carries a current version, so the agent can't be used to sign
+// anything routers would take for something other than a fresh bundle.
+func (a *SigningAgent) checkReseed(su3File *su3.File) error {
+	if 0 != su3File.Format {
+		return fmt.Errorf("su3 format %d is not allowed for reseed", su3File.Format)
+	}
+	if su3File.FileType != su3.FileTypeZIP {
+		return fmt.Errorf("file type %s is not allowed for reseed", su3File.FileType)
+	}
+
+	ts, err := su3File.Timestamp()
+	if nil != err {
+		return err
+	}
+	skew := time.Since(ts)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > a.MaxVersionSkew {
+		return fmt.Errorf("version %s is more than %s from the agent's clock", ts.UTC().Format(time.RFC3339), a.MaxVersionSkew)
+	}
+
+	return nil
+}
+
+// RemoteSigner sends signing requests to a SigningAgent on a unix socket.
+type RemoteSigner struct {
+	Path string
+}
+
+func NewRemoteSigner(path string) *RemoteSigner {
+	return &RemoteSigner{Path: path}
+}
+
+func (s *RemoteSigner) SignSu3(su3File *su3.File) error {
+	client, err := rpc.Dial("unix", s.Path)
+	if nil != err {
+		return fmt.Errorf("unable to reach signing agent: %s", err)
+	}
+	defer client.Close()
+
+	var resp SignResponse
+	req := &SignRequest{
+		Format:      su3File.Format,
+		FileType:    su3File.FileType,
+		ContentType: su3File.ContentType,
+		Version:     su3File.Version,
+		Content:     su3File.Content,
+	}
+	if err := client.Call("SigningAgent.Sign", req, &resp); nil != err {
+		return fmt.Errorf("signing agent: %s", err)
+	}
+
+	su3File.SignatureType = resp.SignatureType
+	su3File.SignerID = resp.SignerID
+	su3File.Signature = resp.Signature
+
+	return nil
+}
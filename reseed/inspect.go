@@ -0,0 +1,47 @@
+package reseed
+
+import (
+	"time"
+)
+
+// SeedInfo describes one routerInfo of a reseed bundle.
+type SeedInfo struct {
+	Name      string
+	Hash      string // base64, as in the file name
+	ZipTime   time.Time
+	Size      int
+	Published time.Time
+	Caps      string
+	Err       error // set when the routerInfo could not be parsed
+}
+
+// InspectSeeds unzips the content of a reseed su3 and describes every
+// routerInfo in it. It doesn't check the routerInfo signatures.
+func InspectSeeds(content []byte) ([]SeedInfo, error) {
+	seeds, err := uzipSeeds(content)
+	if nil != err {
+		return nil, err
+	}
+
+	infos := make([]SeedInfo, len(seeds))
+	for i, seed := range seeds {
+		info := SeedInfo{
+			Name:    seed.Name,
+			ZipTime: seed.ModTime,
+			Size:    len(seed.Data),
+		}
+
+		ri, err := ParseRouterInfo(seed.Data)
+		if nil != err {
+			info.Err = err
+		} else {
+			hash := ri.Hash()
+			info.Hash = i2pBase64.EncodeToString(hash[:])
+			info.Published = ri.Published
+			info.Caps = ri.Options.Get("caps")
+		}
+		infos[i] = info
+	}
+
+	return infos, nil
+}
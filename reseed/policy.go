@@ -0,0 +1,368 @@
+package reseed
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// bandwidthClasses are the bandwidth capabilities from lowest to highest.
+const bandwidthClasses = "KLMNOPX"
+
+// SelectionPolicy decides which verified routerInfos go into reseed bundles,
+// based on the capabilities routers publish in their "caps" option and their
+// "router.version".
+type SelectionPolicy struct {
+	// ExcludeUnreachable leaves out routers publishing 'U'.
+	ExcludeUnreachable bool
+	// ExcludeHidden leaves out routers publishing 'H'.
+	ExcludeHidden bool
+	// MinBandwidth is the lowest bandwidth class (K to X) to include, 0 for
+	// any.
+	MinBandwidth byte
+	// MinVersion is the lowest router.version to include, nil for any.
+	// Routers without a version are left to ExcludeUnversioned.
+	MinVersion RouterVersion
+	// ExcludeUnversioned leaves out routers without a router.version.
+	ExcludeUnversioned bool
+	// FloodfillShare is the share of floodfills to aim for in each su3, 0
+	// for no target.
+	FloodfillShare float64
+	// MaxPerSubnet caps the routers from one IPv4 /16 or IPv6 /48 in each
+	// su3, 0 for no cap. Routers without a published host all count as one
+	// subnet.
+	MaxPerSubnet int
+	// StrictSubnets keeps to MaxPerSubnet even when that leaves su3 files
+	// short, instead of filling them up from subnets that are already full.
+	StrictSubnets bool
+	// BalanceAddresses spreads each su3 evenly over IPv4 and IPv6 and over
+	// the NTCP2 and SSU2 transports. Routers reachable on none of them only
+	// fill up what's left.
+	BalanceAddresses bool
+}
+
+// NewSelectionPolicy returns the default policy, which leaves out
+// unreachable and hidden routers and spreads each su3 over subnets, address
+// families and transports.
+func NewSelectionPolicy() SelectionPolicy {
+	return SelectionPolicy{
+		ExcludeUnreachable: true,
+		ExcludeHidden:      true,
+		MaxPerSubnet:       2,
+		BalanceAddresses:   true,
+	}
+}
+
+// ParseBandwidthClass checks a bandwidth class like "O". An empty class is
+// 0, no minimum.
+func ParseBandwidthClass(class string) (byte, error) {
+	class = strings.ToUpper(class)
+	if class == "" {
+		return 0, nil
+	}
+	if len(class) != 1 || !strings.Contains(bandwidthClasses, class) {
+		return 0, fmt.Errorf("invalid bandwidth class %q (one of %s)", class, bandwidthClasses)
+	}
+	return class[0], nil
+}
+
+// bandwidthClass returns the highest bandwidth class in caps, 0 if there is
+// none.
+func bandwidthClass(caps string) byte {
+	var class byte
+	for i := 0; i < len(caps); i++ {
+		if strings.IndexByte(bandwidthClasses, caps[i]) > strings.IndexByte(bandwidthClasses, class) {
+			class = caps[i]
+		}
+	}
+	return class
+}
+
+// RouterVersion is a dotted router version like 0.9.58.
+type RouterVersion []int
+
+// ParseRouterVersion parses a version like 0.9.58. Anything after the
+// leading digits of a component, like "-rc1", is ignored.
+func ParseRouterVersion(version string) (RouterVersion, error) {
+	var v RouterVersion
+	for _, part := range strings.Split(version, ".") {
+		digits := part
+		if end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			digits = part[:end]
+		}
+		n, err := strconv.Atoi(digits)
+		if nil != err {
+			return nil, fmt.Errorf("invalid router version %q", version)
+		}
+		v = append(v, n)
+	}
+	return v, nil
+}
+
+func (v RouterVersion) String() string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ".")
+}
+
+// newerThan reports whether v is newer than the published version. Versions
+// that can't be parsed count as older.
+func (v RouterVersion) newerThan(version string) bool {
+	other, err := ParseRouterVersion(version)
+	if nil != err {
+		return true
+	}
+	for i := 0; i < len(v) || i < len(other); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+func isFloodfill(ri routerInfo) bool {
+	return strings.ContainsRune(ri.Info.Options.Get("caps"), 'f')
+}
+
+// filter drops the routerInfos the policy excludes and logs how many and
+// why.
+func (p SelectionPolicy) filter(ris []routerInfo) []routerInfo {
+	var (
+		kept                              []routerInfo
+		unreachable, hidden, lowBandwidth int
+		oldVersion, unversioned           int
+	)
+	for _, ri := range ris {
+		caps := ri.Info.Options.Get("caps")
+		version := ri.Info.Options.Get("router.version")
+		switch {
+		case p.ExcludeUnreachable && strings.ContainsRune(caps, 'U'):
+			unreachable++
+		case p.ExcludeHidden && strings.ContainsRune(caps, 'H'):
+			hidden++
+		case 0 != p.MinBandwidth && strings.IndexByte(bandwidthClasses, bandwidthClass(caps)) < strings.IndexByte(bandwidthClasses, p.MinBandwidth):
+			lowBandwidth++
+		case "" == version && p.ExcludeUnversioned:
+			unversioned++
+		case "" != version && nil != p.MinVersion && p.MinVersion.newerThan(version):
+			oldVersion++
+		default:
+			kept = append(kept, ri)
+		}
+	}
+
+	if excluded := len(ris) - len(kept); excluded > 0 {
+		log.Printf("Policy excluded %d of %d routerInfos: %d unreachable, %d hidden, %d below the minimum bandwidth class, %d below the minimum version, %d without a version\n",
+			excluded, len(ris), unreachable, hidden, lowBandwidth, oldVersion, unversioned)
+	}
+
+	return kept
+}
+
+// pick chooses n random routerInfos for one su3, aiming for the
+// FloodfillShare. When one kind runs short the other fills up the rest, and
+// unless StrictSubnets is set, when the subnet cap leaves too few routers the
+// rest is picked without it. overCap is how many were picked that way.
+func (p SelectionPolicy) pick(ris []routerInfo, n int) (seeds []routerInfo, overCap int) {
+	shuffled := make([]routerInfo, len(ris))
+	for i, j := range rand.Perm(len(ris)) {
+		shuffled[i] = ris[j]
+	}
+
+	sel := &selection{
+		policy:  p,
+		chosen:  make(map[string]bool),
+		subnets: make(map[string]int),
+	}
+
+	if p.FloodfillShare > 0 {
+		var floodfills, others []routerInfo
+		for _, ri := range shuffled {
+			if isFloodfill(ri) {
+				floodfills = append(floodfills, ri)
+			} else {
+				others = append(others, ri)
+			}
+		}
+
+		numFloodfills := int(math.Round(float64(n) * math.Min(p.FloodfillShare, 1)))
+		sel.take(floodfills, numFloodfills)
+		sel.take(others, n)
+		sel.take(floodfills, n)
+	} else {
+		sel.take(shuffled, n)
+	}
+
+	if p.StrictSubnets {
+		return sel.seeds, 0
+	}
+
+	capped := len(sel.seeds)
+	sel.relaxed = true
+	sel.take(shuffled, n)
+
+	return sel.seeds, len(sel.seeds) - capped
+}
+
+// selection is the su3 being filled by pick.
+type selection struct {
+	policy  SelectionPolicy
+	seeds   []routerInfo
+	chosen  map[string]bool
+	subnets map[string]int
+	relaxed bool // ignore MaxPerSubnet
+}
+
+// take adds candidates until the selection holds total routerInfos. With
+// BalanceAddresses it goes round robin over the balancedClasses of the
+// candidates, and only falls back to routers without any of them once those
+// run out.
+func (sel *selection) take(candidates []routerInfo, total int) {
+	if !sel.policy.BalanceAddresses {
+		sel.takeInOrder(candidates, total)
+		return
+	}
+
+	buckets := make(map[string][]routerInfo)
+	var others []routerInfo
+	for _, ri := range candidates {
+		classes := addressClasses(ri.Info)
+		if 0 == len(classes) {
+			others = append(others, ri)
+			continue
+		}
+		class := classes[rand.Intn(len(classes))]
+		buckets[class] = append(buckets[class], ri)
+	}
+
+	order := balancedClasses
+	for len(sel.seeds) < total && len(order) > 0 {
+		var left []string
+		for _, class := range order {
+			bucket := buckets[class]
+			for len(bucket) > 0 && !sel.add(bucket[0]) {
+				bucket = bucket[1:]
+			}
+			if len(bucket) > 0 {
+				buckets[class] = bucket[1:]
+				left = append(left, class)
+			}
+			if len(sel.seeds) >= total {
+				return
+			}
+		}
+		order = left
+	}
+
+	sel.takeInOrder(others, total)
+}
+
+// takeInOrder adds candidates in order until the selection holds total
+// routerInfos.
+func (sel *selection) takeInOrder(candidates []routerInfo, total int) {
+	for _, ri := range candidates {
+		if len(sel.seeds) >= total {
+			return
+		}
+		sel.add(ri)
+	}
+}
+
+// add puts ri into the selection unless it is already there or one of its
+// subnets is full.
+func (sel *selection) add(ri routerInfo) bool {
+	if sel.chosen[ri.Name] {
+		return false
+	}
+
+	subnets := subnets(ri.Info)
+	if !sel.relaxed && sel.policy.MaxPerSubnet > 0 {
+		for _, subnet := range subnets {
+			if sel.subnets[subnet] >= sel.policy.MaxPerSubnet {
+				return false
+			}
+		}
+	}
+
+	for _, subnet := range subnets {
+		sel.subnets[subnet]++
+	}
+	sel.chosen[ri.Name] = true
+	sel.seeds = append(sel.seeds, ri)
+	return true
+}
+
+// addressHost returns the IP of a published address, nil for addresses
+// without one (firewalled routers using introducers).
+func addressHost(addr RouterAddress) net.IP {
+	return net.ParseIP(addr.Options.Get("host"))
+}
+
+// balancedClasses are the address family and transport pairs
+// BalanceAddresses spreads each su3 over. Routers pick their transport
+// names, so anything else can't be given a share of its own.
+var balancedClasses = []string{"IPv4/NTCP2", "IPv4/SSU2", "IPv6/NTCP2", "IPv6/SSU2"}
+
+// addressClasses returns the balancedClasses a router can be reached on.
+func addressClasses(ri *RouterInfo) []string {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, addr := range ri.Addresses {
+		ip := addressHost(addr)
+		if nil == ip {
+			continue
+		}
+		family := "IPv6"
+		if nil != ip.To4() {
+			family = "IPv4"
+		}
+
+		class := family + "/" + addr.TransportStyle
+		for _, balanced := range balancedClasses {
+			if class == balanced && !seen[class] {
+				seen[class] = true
+				classes = append(classes, class)
+			}
+		}
+	}
+	return classes
+}
+
+// subnets returns the IPv4 /16 and IPv6 /48 networks of a router's
+// addresses, or "none" when it publishes no host.
+func subnets(ri *RouterInfo) []string {
+	seen := make(map[string]bool)
+	var subnets []string
+	for _, addr := range ri.Addresses {
+		ip := addressHost(addr)
+		if nil == ip {
+			continue
+		}
+
+		subnet := ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+		if ip4 := ip.To4(); nil != ip4 {
+			subnet = ip4.Mask(net.CIDRMask(16, 32)).String() + "/16"
+		}
+		if !seen[subnet] {
+			seen[subnet] = true
+			subnets = append(subnets, subnet)
+		}
+	}
+	if 0 == len(subnets) {
+		subnets = append(subnets, "none")
+	}
+	return subnets
+}
@@ -0,0 +1,118 @@
+package reseed
+
+import (
+	"fmt"
+	"testing"
+)
+
+// testRouter returns a routerInfo reachable on one address.
+func testRouter(name, host, transport string) routerInfo {
+	return routerInfo{
+		Name: name,
+		Info: &RouterInfo{
+			Addresses: []RouterAddress{{
+				TransportStyle: transport,
+				Options:        Mapping{{"host", host}, {"port", "12345"}},
+			}},
+			Options: Mapping{{"caps", "XR"}},
+		},
+	}
+}
+
+// testRouters returns n routers with the transport, each in its own subnet.
+func testRouters(prefix string, n int, ipv6 bool, transport string) []routerInfo {
+	var ris []routerInfo
+	for i := 0; i < n; i++ {
+		host := fmt.Sprintf("%d.%d.1.1", 10+i/256, i%256)
+		if ipv6 {
+			host = fmt.Sprintf("2001:db8:%x::1", i)
+		}
+		ris = append(ris, testRouter(fmt.Sprintf("%s-%d", prefix, i), host, transport))
+	}
+	return ris
+}
+
+func countTransports(seeds []routerInfo) map[string]int {
+	counts := make(map[string]int)
+	for _, seed := range seeds {
+		classes := addressClasses(seed.Info)
+		if 0 == len(classes) {
+			counts["other"]++
+			continue
+		}
+		counts[classes[0]]++
+	}
+	return counts
+}
+
+func TestPickIgnoresMadeUpTransports(t *testing.T) {
+	var ris []routerInfo
+	for i := 0; i < 40; i++ {
+		// every router its own transport, so its own class
+		ris = append(ris, testRouter(fmt.Sprintf("bogus-%d", i), fmt.Sprintf("11.%d.1.1", i), fmt.Sprintf("X%d", i)))
+	}
+	ris = append(ris, testRouters("v4ntcp", 20, false, "NTCP2")...)
+	ris = append(ris, testRouters("v4ssu", 20, false, "SSU2")...)
+	ris = append(ris, testRouters("v6ntcp", 20, true, "NTCP2")...)
+	ris = append(ris, testRouters("v6ssu", 20, true, "SSU2")...)
+
+	for i := 0; i < 20; i++ {
+		seeds, _ := NewSelectionPolicy().pick(ris, 40)
+		if len(seeds) != 40 {
+			t.Fatalf("picked %d routerInfos, want 40", len(seeds))
+		}
+
+		counts := countTransports(seeds)
+		if 0 != counts["other"] {
+			t.Fatalf("picked %d routers with made up transports", counts["other"])
+		}
+		for _, class := range balancedClasses {
+			if counts[class] != 10 {
+				t.Errorf("picked %d %s routers, want 10", counts[class], class)
+			}
+		}
+	}
+}
+
+func TestPickFillsUpWithOtherTransports(t *testing.T) {
+	var ris []routerInfo
+	for i := 0; i < 40; i++ {
+		ris = append(ris, testRouter(fmt.Sprintf("other-%d", i), fmt.Sprintf("11.%d.1.1", i), fmt.Sprintf("X%d", i)))
+	}
+	ris = append(ris, testRouters("v4ntcp", 8, false, "NTCP2")...)
+
+	seeds, _ := NewSelectionPolicy().pick(ris, 20)
+	if len(seeds) != 20 {
+		t.Fatalf("picked %d routerInfos, want 20", len(seeds))
+	}
+	if counts := countTransports(seeds); counts["IPv4/NTCP2"] != 8 || counts["other"] != 12 {
+		t.Errorf("picked %v, want all 8 IPv4/NTCP2 routers and 12 others", counts)
+	}
+}
+
+func TestPickCapsRoutersWithoutHost(t *testing.T) {
+	var ris []routerInfo
+	for i := 0; i < 10; i++ {
+		ris = append(ris, routerInfo{
+			Name: fmt.Sprintf("firewalled-%d", i),
+			Info: &RouterInfo{Addresses: []RouterAddress{{TransportStyle: "SSU2", Options: Mapping{{"caps", "4"}}}}},
+		})
+	}
+	ris = append(ris, testRouters("v4ntcp", 10, false, "NTCP2")...)
+
+	policy := NewSelectionPolicy()
+	policy.StrictSubnets = true
+	seeds, overCap := policy.pick(ris, 20)
+	if len(seeds) != 12 || 0 != overCap {
+		t.Fatalf("picked %d routerInfos, %d over the cap, want 12 and none", len(seeds), overCap)
+	}
+	if counts := countTransports(seeds); counts["other"] != policy.MaxPerSubnet {
+		t.Errorf("picked %d routers without a host, want %d", counts["other"], policy.MaxPerSubnet)
+	}
+
+	policy.StrictSubnets = false
+	seeds, overCap = policy.pick(ris, 20)
+	if len(seeds) != 20 || 8 != overCap {
+		t.Errorf("picked %d routerInfos, %d over the cap, want 20 and 8", len(seeds), overCap)
+	}
+}
@@ -0,0 +1,420 @@
+package reseed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// i2pBase64 is the base64 alphabet I2P uses for router hashes, as in the
+// routerInfo-<hash>.dat file names.
+var i2pBase64 = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~")
+
+var errShortRouterInfo = errors.New("routerInfo too short")
+
+// Certificate types of a RouterIdentity.
+const (
+	CertTypeNull = uint8(0)
+	CertTypeKey  = uint8(5)
+)
+
+// Signing key types of a key certificate.
+const (
+	SigningKeyDSASHA1         = uint16(0)
+	SigningKeyECDSASHA256P256 = uint16(1)
+	SigningKeyECDSASHA384P384 = uint16(2)
+	SigningKeyECDSASHA512P521 = uint16(3)
+	SigningKeyRSASHA2562048   = uint16(4)
+	SigningKeyRSASHA3843072   = uint16(5)
+	SigningKeyRSASHA5124096   = uint16(6)
+	SigningKeyEd25519         = uint16(7)
+	SigningKeyEd25519ph       = uint16(8)
+	SigningKeyRedDSA          = uint16(11)
+)
+
+// Crypto key types of a key certificate.
+const (
+	CryptoKeyElGamal = uint16(0)
+	CryptoKeyP256    = uint16(1)
+	CryptoKeyP384    = uint16(2)
+	CryptoKeyP521    = uint16(3)
+	CryptoKeyX25519  = uint16(4)
+)
+
+const (
+	publicKeyFieldLength  = 256
+	signingKeyFieldLength = 128
+	keyCertPayloadLength  = 4
+)
+
+// signingKeyLengths are the public key and signature lengths of each
+// signing key type.
+var signingKeyLengths = map[uint16]struct{ PublicKey, Signature int }{
+	SigningKeyDSASHA1:         {128, 40},
+	SigningKeyECDSASHA256P256: {64, 64},
+	SigningKeyECDSASHA384P384: {96, 96},
+	SigningKeyECDSASHA512P521: {132, 132},
+	SigningKeyRSASHA2562048:   {256, 256},
+	SigningKeyRSASHA3843072:   {384, 384},
+	SigningKeyRSASHA5124096:   {512, 512},
+	SigningKeyEd25519:         {32, 64},
+	SigningKeyEd25519ph:       {32, 64},
+	SigningKeyRedDSA:          {32, 64},
+}
+
+// cryptoKeyLengths are the public key lengths of each crypto key type.
+var cryptoKeyLengths = map[uint16]int{
+	CryptoKeyElGamal: 256,
+	CryptoKeyP256:    64,
+	CryptoKeyP384:    96,
+	CryptoKeyP521:    132,
+	CryptoKeyX25519:  32,
+}
+
+// Certificate is the certificate of a RouterIdentity. Routers use either a
+// null certificate or a key certificate.
+type Certificate struct {
+	Type    uint8
+	Payload []byte
+}
+
+// RouterIdentity is the public part of a router's keys. PublicKey and
+// SigningKey hold the complete fixed size fields, including padding.
+type RouterIdentity struct {
+	PublicKey   []byte
+	SigningKey  []byte
+	Certificate Certificate
+}
+
+// SigningKeyType is the signing key type of the key certificate, DSA-SHA1
+// without one.
+func (id *RouterIdentity) SigningKeyType() uint16 {
+	if id.Certificate.Type != CertTypeKey || len(id.Certificate.Payload) < keyCertPayloadLength {
+		return SigningKeyDSASHA1
+	}
+	return binary.BigEndian.Uint16(id.Certificate.Payload)
+}
+
+// CryptoKeyType is the crypto key type of the key certificate, ElGamal
+// without one.
+func (id *RouterIdentity) CryptoKeyType() uint16 {
+	if id.Certificate.Type != CertTypeKey || len(id.Certificate.Payload) < keyCertPayloadLength {
+		return CryptoKeyElGamal
+	}
+	return binary.BigEndian.Uint16(id.Certificate.Payload[2:])
+}
+
+// SigningPublicKey returns the signing public key without padding. Keys
+// shorter than their field are right aligned in it, longer keys continue in
+// the key certificate.
+func (id *RouterIdentity) SigningPublicKey() ([]byte, error) {
+	sigType := id.SigningKeyType()
+	lengths, ok := signingKeyLengths[sigType]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key type %d", sigType)
+	}
+
+	if lengths.PublicKey <= signingKeyFieldLength {
+		return id.SigningKey[signingKeyFieldLength-lengths.PublicKey:], nil
+	}
+
+	extra := lengths.PublicKey - signingKeyFieldLength
+	payload := id.Certificate.Payload
+	if len(payload) < keyCertPayloadLength+extra {
+		return nil, fmt.Errorf("key certificate too short for signing key type %d", sigType)
+	}
+	key := append([]byte{}, id.SigningKey...)
+	return append(key, payload[keyCertPayloadLength:keyCertPayloadLength+extra]...), nil
+}
+
+// CryptoPublicKey returns the encryption public key without padding. Keys
+// are left aligned in their field.
+func (id *RouterIdentity) CryptoPublicKey() ([]byte, error) {
+	cryptoType := id.CryptoKeyType()
+	length, ok := cryptoKeyLengths[cryptoType]
+	if !ok {
+		return nil, fmt.Errorf("unknown crypto key type %d", cryptoType)
+	}
+	return id.PublicKey[:length], nil
+}
+
+// Hash is the SHA-256 of the serialized identity, the router's name in the
+// netDb.
+func (id *RouterIdentity) Hash() [32]byte {
+	var b bytes.Buffer
+	id.writeTo(&b)
+	return sha256.Sum256(b.Bytes())
+}
+
+func (id *RouterIdentity) writeTo(b *bytes.Buffer) {
+	b.Write(id.PublicKey)
+	b.Write(id.SigningKey)
+	b.WriteByte(id.Certificate.Type)
+	binary.Write(b, binary.BigEndian, uint16(len(id.Certificate.Payload)))
+	b.Write(id.Certificate.Payload)
+}
+
+// Option is one key/value pair of a Mapping.
+type Option struct {
+	Key   string
+	Value string
+}
+
+// Mapping is an I2P Mapping. The order is kept because the router signed
+// it as it is.
+type Mapping []Option
+
+// Get returns the value of key, or "" if there is none.
+func (m Mapping) Get(key string) string {
+	for _, o := range m {
+		if o.Key == key {
+			return o.Value
+		}
+	}
+	return ""
+}
+
+// RouterAddress is one way to reach a router. A zero Expiration means the
+// address doesn't expire.
+type RouterAddress struct {
+	Cost           uint8
+	Expiration     time.Time
+	TransportStyle string
+	Options        Mapping
+}
+
+// RouterInfo is a router's signed netDb entry.
+type RouterInfo struct {
+	Identity  RouterIdentity
+	Published time.Time
+	Addresses []RouterAddress
+	Peers     [][]byte
+	Options   Mapping
+	Signature []byte
+}
+
+// ParseRouterInfo decodes a routerInfo. It doesn't check the signature.
+func ParseRouterInfo(data []byte) (*RouterInfo, error) {
+	ri := new(RouterInfo)
+	if err := ri.UnmarshalBinary(data); nil != err {
+		return nil, err
+	}
+	return ri, nil
+}
+
+// Hash is the hash of the router's identity.
+func (ri *RouterInfo) Hash() [32]byte {
+	return ri.Identity.Hash()
+}
+
+// UnmarshalBinary decodes a routerInfo into ri.
+func (ri *RouterInfo) UnmarshalBinary(data []byte) error {
+	b := data
+	if len(b) < publicKeyFieldLength+signingKeyFieldLength+3 {
+		return errShortRouterInfo
+	}
+	ri.Identity.PublicKey = b[:publicKeyFieldLength]
+	ri.Identity.SigningKey = b[publicKeyFieldLength : publicKeyFieldLength+signingKeyFieldLength]
+	b = b[publicKeyFieldLength+signingKeyFieldLength:]
+
+	ri.Identity.Certificate.Type = b[0]
+	certLength := int(binary.BigEndian.Uint16(b[1:]))
+	if len(b) < 3+certLength {
+		return errShortRouterInfo
+	}
+	ri.Identity.Certificate.Payload = b[3 : 3+certLength]
+	b = b[3+certLength:]
+
+	if len(b) < 9 {
+		return errShortRouterInfo
+	}
+	ri.Published = i2pDate(b)
+
+	addresses := int(b[8])
+	b = b[9:]
+	ri.Addresses = make([]RouterAddress, addresses)
+	for i := range ri.Addresses {
+		addr := &ri.Addresses[i]
+		if len(b) < 9 {
+			return errShortRouterInfo
+		}
+		addr.Cost = b[0]
+		addr.Expiration = i2pDate(b[1:])
+
+		var err error
+		if addr.TransportStyle, b, err = readI2PString(b[9:]); nil != err {
+			return err
+		}
+		if addr.Options, b, err = readMapping(b); nil != err {
+			return err
+		}
+	}
+
+	if len(b) < 1 || len(b) < 1+32*int(b[0]) {
+		return errShortRouterInfo
+	}
+	ri.Peers = make([][]byte, b[0])
+	for i := range ri.Peers {
+		ri.Peers[i] = b[1+32*i : 1+32*(i+1)]
+	}
+	b = b[1+32*len(ri.Peers):]
+
+	var err error
+	if ri.Options, b, err = readMapping(b); nil != err {
+		return err
+	}
+
+	sigType := ri.Identity.SigningKeyType()
+	lengths, ok := signingKeyLengths[sigType]
+	if !ok {
+		return fmt.Errorf("unknown signing key type %d", sigType)
+	}
+	if len(b) < lengths.Signature {
+		return errShortRouterInfo
+	}
+	if len(b) > lengths.Signature {
+		return fmt.Errorf("%d unexpected bytes after the routerInfo signature", len(b)-lengths.Signature)
+	}
+	ri.Signature = b
+
+	return nil
+}
+
+// MarshalBinary encodes ri in the format UnmarshalBinary reads.
+func (ri *RouterInfo) MarshalBinary() ([]byte, error) {
+	b, err := ri.signedBytes()
+	if nil != err {
+		return nil, err
+	}
+	return append(b, ri.Signature...), nil
+}
+
+// signedBytes encodes everything of ri the signature covers.
+func (ri *RouterInfo) signedBytes() ([]byte, error) {
+	if len(ri.Identity.PublicKey) != publicKeyFieldLength || len(ri.Identity.SigningKey) != signingKeyFieldLength {
+		return nil, errors.New("routerInfo identity has invalid key field lengths")
+	}
+	if len(ri.Addresses) > 255 || len(ri.Peers) > 255 {
+		return nil, errors.New("routerInfo has too many addresses or peers")
+	}
+
+	var b bytes.Buffer
+	ri.Identity.writeTo(&b)
+	writeI2PDate(&b, ri.Published)
+
+	b.WriteByte(uint8(len(ri.Addresses)))
+	for _, addr := range ri.Addresses {
+		b.WriteByte(addr.Cost)
+		writeI2PDate(&b, addr.Expiration)
+		if err := writeI2PString(&b, addr.TransportStyle); nil != err {
+			return nil, err
+		}
+		if err := writeMapping(&b, addr.Options); nil != err {
+			return nil, err
+		}
+	}
+
+	b.WriteByte(uint8(len(ri.Peers)))
+	for _, peer := range ri.Peers {
+		b.Write(peer)
+	}
+
+	if err := writeMapping(&b, ri.Options); nil != err {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// i2pDate reads an 8 byte I2P Date, milliseconds since the epoch. 0 is the
+// zero time.
+func i2pDate(b []byte) time.Time {
+	ms := int64(binary.BigEndian.Uint64(b))
+	if 0 == ms {
+		return time.Time{}
+	}
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+func writeI2PDate(b *bytes.Buffer, t time.Time) {
+	var ms int64
+	if !t.IsZero() {
+		ms = t.UnixNano() / int64(time.Millisecond)
+	}
+	binary.Write(b, binary.BigEndian, ms)
+}
+
+// readI2PString reads a length prefixed I2P String.
+func readI2PString(b []byte) (string, []byte, error) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return "", nil, errShortRouterInfo
+	}
+	n := int(b[0])
+	return string(b[1 : 1+n]), b[1+n:], nil
+}
+
+func writeI2PString(b *bytes.Buffer, s string) error {
+	if len(s) > 255 {
+		return fmt.Errorf("string too long: %d > 255 bytes", len(s))
+	}
+	b.WriteByte(uint8(len(s)))
+	b.WriteString(s)
+	return nil
+}
+
+// readMapping reads an I2P Mapping, a 2 byte length followed by
+// key=value; pairs of I2P Strings.
+func readMapping(b []byte) (Mapping, []byte, error) {
+	if len(b) < 2 || len(b) < 2+int(binary.BigEndian.Uint16(b)) {
+		return nil, nil, errShortRouterInfo
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	m, rest := b[2:2+n], b[2+n:]
+
+	var mapping Mapping
+	for len(m) > 0 {
+		key, r, err := readI2PString(m)
+		if nil != err {
+			return nil, nil, err
+		}
+		if !bytes.HasPrefix(r, []byte("=")) {
+			return nil, nil, fmt.Errorf("mapping: missing '=' after %q", key)
+		}
+		value, r, err := readI2PString(r[1:])
+		if nil != err {
+			return nil, nil, err
+		}
+		if !bytes.HasPrefix(r, []byte(";")) {
+			return nil, nil, fmt.Errorf("mapping: missing ';' after %q", key)
+		}
+		mapping = append(mapping, Option{key, value})
+		m = r[1:]
+	}
+
+	return mapping, rest, nil
+}
+
+func writeMapping(b *bytes.Buffer, mapping Mapping) error {
+	var m bytes.Buffer
+	for _, o := range mapping {
+		if err := writeI2PString(&m, o.Key); nil != err {
+			return err
+		}
+		m.WriteByte('=')
+		if err := writeI2PString(&m, o.Value); nil != err {
+			return err
+		}
+		m.WriteByte(';')
+	}
+	if m.Len() > 65535 {
+		return fmt.Errorf("mapping too long: %d bytes", m.Len())
+	}
+
+	binary.Write(b, binary.BigEndian, uint16(m.Len()))
+	b.Write(m.Bytes())
+	return nil
+}
@@ -0,0 +1,161 @@
+package reseed
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// routerInfoFixtures are the routerInfos testdata/generated/gen.go wrote,
+// named after the hash of their identity like in a router's netDb.
+var routerInfoFixtures = []struct {
+	file       string
+	sigType    uint16
+	cryptoType uint16
+	addresses  int
+	caps       string
+}{
+	{"generated/routerInfo-l619dxKEfZGwSxAFNyepbVMBTPek2JqXkBcLEsTzBow=.dat", SigningKeyDSASHA1, CryptoKeyElGamal, 1, "LR"},
+	{"generated/routerInfo-kQcUqoL4pylyrY1CTDwazEhvHprlFVh3Pv9AbN7R9jM=.dat", SigningKeyECDSASHA256P256, CryptoKeyElGamal, 1, "NR"},
+	{"generated/routerInfo-zuVyoBKjjibmlBECsIVDOLVo-E6zyuG260nY07QLW8o=.dat", SigningKeyECDSASHA512P521, CryptoKeyElGamal, 2, "OR"},
+	{"generated/routerInfo-1KYS610Y~~qnJP6CwjFL79uMbuSluKFM-0pTr1cc6Po=.dat", SigningKeyEd25519, CryptoKeyX25519, 3, "XfR"},
+}
+
+func readFixture(t *testing.T, file string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if nil != err {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseRouterInfo(t *testing.T) {
+	published := time.Date(2026, 10, 16, 21, 4, 17, 312e6, time.UTC)
+
+	for _, fixture := range routerInfoFixtures {
+		data := readFixture(t, fixture.file)
+		ri, err := ParseRouterInfo(data)
+		if nil != err {
+			t.Errorf("%s: %s", fixture.file, err)
+			continue
+		}
+
+		if sigType := ri.Identity.SigningKeyType(); sigType != fixture.sigType {
+			t.Errorf("%s: signing key type %d, want %d", fixture.file, sigType, fixture.sigType)
+		}
+		if cryptoType := ri.Identity.CryptoKeyType(); cryptoType != fixture.cryptoType {
+			t.Errorf("%s: crypto key type %d, want %d", fixture.file, cryptoType, fixture.cryptoType)
+		}
+		if pub, err := ri.Identity.SigningPublicKey(); nil != err || len(pub) != signingKeyLengths[fixture.sigType].PublicKey {
+			t.Errorf("%s: signing public key of %d bytes (%v)", fixture.file, len(pub), err)
+		}
+		if !ri.Published.Equal(published) {
+			t.Errorf("%s: published %s, want %s", fixture.file, ri.Published, published)
+		}
+		if len(ri.Addresses) != fixture.addresses {
+			t.Errorf("%s: %d addresses, want %d", fixture.file, len(ri.Addresses), fixture.addresses)
+		}
+		if caps := ri.Options.Get("caps"); caps != fixture.caps {
+			t.Errorf("%s: caps %q, want %q", fixture.file, caps, fixture.caps)
+		}
+
+	}
+}
+
+// TestRouterInfoFiles checks every routerInfo in testdata, including those
+// copied from a live netDb into testdata/captured: it has to parse, marshal
+// back to the same bytes, hash to its file name and carry a valid signature.
+func TestRouterInfoFiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "routerInfo-*.dat"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 0 == len(files) {
+		t.Fatal("no routerInfos in testdata")
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if nil != err {
+			t.Fatal(err)
+		}
+		ri, err := ParseRouterInfo(data)
+		if nil != err {
+			t.Errorf("%s: %s", file, err)
+			continue
+		}
+
+		hash := ri.Hash()
+		if name := "routerInfo-" + i2pBase64.EncodeToString(hash[:]) + ".dat"; name != filepath.Base(file) {
+			t.Errorf("%s: hash gives %s", file, name)
+		}
+
+		marshaled, err := ri.MarshalBinary()
+		if nil != err {
+			t.Errorf("%s: %s", file, err)
+		} else if !bytes.Equal(marshaled, data) {
+			t.Errorf("%s: MarshalBinary doesn't give back the parsed bytes", file)
+		}
+
+		if err := ri.VerifySignature(); nil != err {
+			t.Errorf("%s: %s", file, err)
+		}
+	}
+}
+
+func TestParseRouterInfoP521Overflow(t *testing.T) {
+	ri, err := ParseRouterInfo(readFixture(t, routerInfoFixtures[2].file))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// the 4 bytes of the 132 byte P-521 key that don't fit the signing key
+	// field follow the types in the key certificate
+	if n := len(ri.Identity.Certificate.Payload); n != 8 {
+		t.Fatalf("key certificate payload of %d bytes, want 8", n)
+	}
+	pub, err := ri.Identity.SigningPublicKey()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pub[:128], ri.Identity.SigningKey) || !bytes.Equal(pub[128:], ri.Identity.Certificate.Payload[4:]) {
+		t.Error("P-521 signing public key is not the signing key field followed by the certificate overflow")
+	}
+}
+
+func TestParseRouterInfoTruncated(t *testing.T) {
+	data := readFixture(t, routerInfoFixtures[3].file)
+
+	for _, n := range []int{0, 100, 390, len(data) - 1} {
+		if _, err := ParseRouterInfo(data[:n]); nil == err {
+			t.Errorf("parsed %d of %d bytes", n, len(data))
+		}
+	}
+	if _, err := ParseRouterInfo(append(data, 0)); nil == err {
+		t.Error("parsed a routerInfo with a trailing byte")
+	}
+}
+
+func TestVerifySignatureTampered(t *testing.T) {
+	for _, fixture := range routerInfoFixtures {
+		data := readFixture(t, fixture.file)
+		// claim to be a floodfill, keeping the length of the caps
+		caps := "caps=" + string(rune(len(fixture.caps)))
+		tampered := bytes.Replace(data, []byte(caps+fixture.caps), []byte(caps+strings.Repeat("f", len(fixture.caps))), 1)
+		if bytes.Equal(tampered, data) {
+			t.Fatalf("%s: caps not found", fixture.file)
+		}
+
+		ri, err := ParseRouterInfo(tampered)
+		if nil != err {
+			t.Fatalf("%s: %s", fixture.file, err)
+		}
+		if err := ri.VerifySignature(); !errors.Is(err, ErrRouterInfoSignature) {
+			t.Errorf("%s: tampered routerInfo gave %v", fixture.file, err)
+		}
+	}
+}
@@ -1,14 +1,12 @@
 package reseed
 
 import (
-	"crypto/rsa"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"hash/crc32"
 	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -22,6 +20,7 @@ type routerInfo struct {
 	Name    string
 	ModTime time.Time
 	Data    []byte
+	Info    *RouterInfo // the parsed Data, nil if it couldn't be parsed
 }
 
 type Peer string
@@ -39,11 +38,12 @@ type Reseeder interface {
 }
 
 type ReseederImpl struct {
-	netdb NetDbProvider
-	su3s  chan [][]byte
+	netdb     NetDbProvider
+	su3s      chan [][]byte
+	rebuildMu sync.Mutex
 
-	SigningKey      *rsa.PrivateKey
-	SignerID        []byte
+	Signer          Su3Signer
+	Policy          SelectionPolicy
 	NumRi           int
 	RebuildInterval time.Duration
 	NumSu3          int
@@ -53,6 +53,7 @@ func NewReseeder(netdb NetDbProvider) *ReseederImpl {
 	return &ReseederImpl{
 		netdb:           netdb,
 		su3s:            make(chan [][]byte),
+		Policy:          NewSelectionPolicy(),
 		NumRi:           77,
 		RebuildInterval: 90 * time.Hour,
 	}
@@ -96,7 +97,16 @@ func (rs *ReseederImpl) Start() chan bool {
 	return quit
 }
 
+// Rebuild replaces the su3 cache right away, for example after the signing
+// identities changed. The current cache is served until the new one is ready.
+func (rs *ReseederImpl) Rebuild() error {
+	return rs.rebuild()
+}
+
 func (rs *ReseederImpl) rebuild() error {
+	rs.rebuildMu.Lock()
+	defer rs.rebuildMu.Unlock()
+
 	log.Println("Rebuilding su3 cache...")
 
 	// get all RIs from netdb provider
@@ -105,6 +115,10 @@ func (rs *ReseederImpl) rebuild() error {
 		return fmt.Errorf("Unable to get routerInfos: %s", err)
 	}
 
+	// drop corrupt and forged routerInfos
+	ris = verifyRouterInfos(ris)
+	ris = rs.Policy.filter(ris)
+
 	// use only 75% of routerInfos
 	ris = ris[len(ris)/4:]
 
@@ -113,10 +127,27 @@ func (rs *ReseederImpl) rebuild() error {
 		return fmt.Errorf("not enough routerInfos - have: %d, need: %d", len(ris), rs.NumRi)
 	}
 
+	// count the su3 files that couldn't be built, ex. when the signer is
+	// down or over its rate limit
+	var (
+		failedMu sync.Mutex
+		failed   int
+		firstErr error
+	)
+	onError := func(err error) {
+		failedMu.Lock()
+		defer failedMu.Unlock()
+		if 0 == failed {
+			firstErr = err
+		}
+		failed++
+	}
+
 	// build a pipeline ris -> seeds -> su3
-	seedsChan := rs.seedsProducer(ris)
+	numSu3s := rs.numSu3s(len(ris))
+	seedsChan := rs.seedsProducer(ris, numSu3s)
 	// fan-in multiple builders
-	su3Chan := fanIn(rs.su3Builder(seedsChan), rs.su3Builder(seedsChan), rs.su3Builder(seedsChan))
+	su3Chan := fanIn(rs.su3Builder(seedsChan, onError), rs.su3Builder(seedsChan, onError), rs.su3Builder(seedsChan, onError))
 
 	// read from su3 chan and append to su3s slice
 	var newSu3s [][]byte
@@ -129,6 +160,15 @@ func (rs *ReseederImpl) rebuild() error {
 		newSu3s = append(newSu3s, data)
 	}
 
+	// keep serving the current su3s unless at least half of the new ones
+	// could be built
+	if failed > 0 {
+		if len(newSu3s) < (numSu3s+1)/2 {
+			return fmt.Errorf("Kept the current su3 cache, only %d of %d su3 files could be built: %s", len(newSu3s), numSu3s, firstErr)
+		}
+		log.Printf("%d of %d su3 files could not be built: %s\n", failed, numSu3s, firstErr)
+	}
+
 	// use this new set of su3s
 	rs.su3s <- newSu3s
 
@@ -137,55 +177,67 @@ func (rs *ReseederImpl) rebuild() error {
 	return nil
 }
 
-func (rs *ReseederImpl) seedsProducer(ris []routerInfo) <-chan []routerInfo {
-	lenRis := len(ris)
-
-	// if NumSu3 is not specified, then we determine the "best" number based on the number of RIs
-	var numSu3s int
+// numSu3s is NumSu3, or if it is not specified the "best" number based on
+// the number of RIs.
+func (rs *ReseederImpl) numSu3s(lenRis int) int {
 	if rs.NumSu3 != 0 {
-		numSu3s = rs.NumSu3
-	} else {
-		switch {
-		case lenRis > 4000:
-			numSu3s = 300
-		case lenRis > 3000:
-			numSu3s = 200
-		case lenRis > 2000:
-			numSu3s = 100
-		case lenRis > 1000:
-			numSu3s = 75
-		default:
-			numSu3s = 50
-		}
+		return rs.NumSu3
 	}
 
+	switch {
+	case lenRis > 4000:
+		return 300
+	case lenRis > 3000:
+		return 200
+	case lenRis > 2000:
+		return 100
+	case lenRis > 1000:
+		return 75
+	default:
+		return 50
+	}
+}
+
+func (rs *ReseederImpl) seedsProducer(ris []routerInfo, numSu3s int) <-chan []routerInfo {
+	lenRis := len(ris)
+
 	log.Printf("Building %d su3 files each containing %d out of %d routerInfos.\n", numSu3s, rs.NumRi, lenRis)
 
 	out := make(chan []routerInfo)
 
 	go func() {
+		var short, overCap, routersOverCap int
 		for i := 0; i < numSu3s; i++ {
-			var seeds []routerInfo
-			unsorted := rand.Perm(lenRis)
-			for z := 0; z < rs.NumRi; z++ {
-				seeds = append(seeds, ris[unsorted[z]])
+			seeds, n := rs.Policy.pick(ris, rs.NumRi)
+			if len(seeds) < rs.NumRi {
+				short++
+			}
+			if n > 0 {
+				overCap++
+				routersOverCap += n
 			}
-
 			out <- seeds
 		}
+
+		if short > 0 {
+			log.Printf("%d of %d su3 files hold fewer than %d routerInfos because of the subnet cap\n", short, numSu3s, rs.NumRi)
+		}
+		if overCap > 0 {
+			log.Printf("%d of %d su3 files were filled up with %d routerInfos past the subnet cap\n", overCap, numSu3s, routersOverCap)
+		}
 		close(out)
 	}()
 
 	return out
 }
 
-func (rs *ReseederImpl) su3Builder(in <-chan []routerInfo) <-chan *su3.File {
+func (rs *ReseederImpl) su3Builder(in <-chan []routerInfo, onError func(error)) <-chan *su3.File {
 	out := make(chan *su3.File)
 	go func() {
 		for seeds := range in {
 			gs, err := rs.createSu3(seeds)
 			if nil != err {
-				log.Println(err)
+				onError(err)
 				continue
 			}
 
@@ -218,8 +270,9 @@ func (rs *ReseederImpl) createSu3(seeds []routerInfo) (*su3.File, error) {
 	}
 	su3File.Content = zipped
 
-	su3File.SignerID = rs.SignerID
-	su3File.Sign(rs.SigningKey)
+	if err := rs.Signer.SignSu3(su3File); nil != err {
+		return nil, err
+	}
 
 	return su3File, nil
 }
@@ -231,14 +284,23 @@ type NetDbProvider interface {
 
 type LocalNetDbImpl struct {
 	Path string
+	// MaxAge is how long after it was published a routerInfo is still
+	// served. The file time is only used for routerInfos that can't be
+	// parsed.
+	MaxAge time.Duration
 }
 
 func NewLocalNetDb(path string) *LocalNetDbImpl {
 	return &LocalNetDbImpl{
-		Path: path,
+		Path:   path,
+		MaxAge: 192 * time.Hour,
 	}
 }
 
+// maxPublishedSkew is how far in the future a routerInfo may be published
+// before it is considered misdated.
+const maxPublishedSkew = time.Hour
+
 func (db *LocalNetDbImpl) RouterInfos() (routerInfos []routerInfo, err error) {
 	r, _ := regexp.Compile("^routerInfo-[A-Za-z0-9-=~]+.dat$")
 
@@ -252,6 +314,8 @@ func (db *LocalNetDbImpl) RouterInfos() (routerInfos []routerInfo, err error) {
 
 	filepath.Walk(db.Path, walkpath)
 
+	now := time.Now()
+	var expired, expiredByFileTime, future int
 	for path, file := range files {
 		riBytes, err := ioutil.ReadFile(path)
 		if nil != err {
@@ -259,19 +323,44 @@ func (db *LocalNetDbImpl) RouterInfos() (routerInfos []routerInfo, err error) {
 			continue
 		}
 
-		// ignore outdate routerInfos
-		age := time.Since(file.ModTime())
-		if age.Hours() > 192 {
+		// ignore outdated routerInfos, by the date the router published
+		// them or the file time if they can't be parsed
+		ri, err := ParseRouterInfo(riBytes)
+		if nil != err {
+			if now.Sub(file.ModTime()) > db.MaxAge {
+				expiredByFileTime++
+				continue
+			}
+			// left for verifyRouterInfos to reject
+			routerInfos = append(routerInfos, routerInfo{
+				Name:    file.Name(),
+				ModTime: file.ModTime(),
+				Data:    riBytes,
+			})
+			continue
+		}
+		if ri.Published.After(now.Add(maxPublishedSkew)) {
+			future++
+			continue
+		}
+		if now.Sub(ri.Published) > db.MaxAge {
+			expired++
 			continue
 		}
 
 		routerInfos = append(routerInfos, routerInfo{
 			Name:    file.Name(),
-			ModTime: file.ModTime(),
+			ModTime: ri.Published,
 			Data:    riBytes,
+			Info:    ri,
 		})
 	}
 
+	if skipped := expired + expiredByFileTime + future; skipped > 0 {
+		log.Printf("Skipped %d of %d routerInfos: %d published more than %s ago, %d unparsable with an old file time, %d published in the future\n",
+			skipped, len(files), expired, db.MaxAge, expiredByFileTime, future)
+	}
+
 	return
 }
 
@@ -0,0 +1,112 @@
+package reseed
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/su3"
+)
+
+// fixtureNetDb serves the routerInfo fixtures.
+type fixtureNetDb struct {
+	t *testing.T
+}
+
+func (db fixtureNetDb) RouterInfos() ([]routerInfo, error) {
+	var ris []routerInfo
+	for _, fixture := range routerInfoFixtures {
+		ris = append(ris, routerInfo{Name: fixture.file, Data: readFixture(db.t, fixture.file)})
+	}
+	return ris, nil
+}
+
+// failingSigner signs with signer until fail is set.
+type failingSigner struct {
+	signer Su3Signer
+	fail   bool
+}
+
+func (s *failingSigner) SignSu3(su3File *su3.File) error {
+	if s.fail {
+		return errors.New("signing agent: rate limit of 1500 signatures per hour reached")
+	}
+	return s.signer.SignSu3(su3File)
+}
+
+func TestRebuildKeepsCacheWhenSigningFails(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	signer := &failingSigner{signer: NewKeySigner(key, []byte("test@mail.i2p"))}
+
+	rs := NewReseeder(fixtureNetDb{t})
+	rs.Signer = signer
+	rs.NumRi = 2
+	rs.NumSu3 = 5
+	quit := rs.Start()
+	defer close(quit)
+
+	before, err := rs.PeerSu3Bytes(Peer("127.0.0.1"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	signer.fail = true
+	if err := rs.Rebuild(); nil == err {
+		t.Error("rebuild without a working signer succeeded")
+	}
+
+	after, err := rs.PeerSu3Bytes(Peer("127.0.0.1"))
+	if nil != err {
+		t.Fatalf("su3 cache was dropped: %s", err)
+	}
+	if string(before) != string(after) {
+		t.Error("su3 cache was replaced")
+	}
+}
+
+func TestLocalNetDbFileTimeFallback(t *testing.T) {
+	dir := t.TempDir()
+	// keep the fixtures, whatever the clock says
+	published := time.Date(2026, 10, 16, 21, 4, 17, 312e6, time.UTC)
+	db := &LocalNetDbImpl{Path: dir, MaxAge: time.Since(published) + 24*time.Hour}
+
+	write := func(name string, data []byte, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0644); nil != err {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); nil != err {
+			t.Fatal(err)
+		}
+	}
+	// the file time of a routerInfo that parses doesn't count
+	write("routerInfo-valid.dat", readFixture(t, routerInfoFixtures[3].file), time.Now().Add(-2*db.MaxAge))
+	write("routerInfo-fresh.dat", []byte("garbage"), time.Now())
+	write("routerInfo-old.dat", []byte("garbage"), time.Now().Add(-2*db.MaxAge))
+
+	ris, err := db.RouterInfos()
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	for _, ri := range ris {
+		names[ri.Name] = true
+	}
+	if len(ris) != 2 || !names["routerInfo-valid.dat"] || !names["routerInfo-fresh.dat"] {
+		t.Fatalf("got routerInfos %v, want the valid one and the fresh unparsable one", names)
+	}
+
+	valid := verifyRouterInfos(ris)
+	if len(valid) != 1 || valid[0].Name != "routerInfo-valid.dat" {
+		t.Errorf("verified %d routerInfos, want only the valid one", len(valid))
+	}
+}
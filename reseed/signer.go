@@ -0,0 +1,139 @@
+package reseed
+
+import (
+	"crypto"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MDrollette/i2p-tools/su3"
+)
+
+// Su3Signer signs su3 files on behalf of the reseeder. It sets the signer
+// ID, the signature type and the signature.
+type Su3Signer interface {
+	SignSu3(su3File *su3.File) error
+}
+
+// KeySigner signs with a key held in memory.
+type KeySigner struct {
+	Key      crypto.Signer
+	SignerID []byte
+}
+
+func NewKeySigner(key crypto.Signer, signerID []byte) *KeySigner {
+	return &KeySigner{Key: key, SignerID: signerID}
+}
+
+func (s *KeySigner) SignSu3(su3File *su3.File) error {
+	sigType, err := su3.SignatureTypeForKey(s.Key.Public())
+	if nil != err {
+		return err
+	}
+
+	su3File.SignatureType = sigType
+	su3File.SignerID = s.SignerID
+
+	return su3File.Sign(s.Key)
+}
+
+// SigningIdentity is a signer that may be used between NotBefore and
+// NotAfter. A zero time leaves that end of the window open.
+type SigningIdentity struct {
+	Name      string
+	Signer    Su3Signer
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+func (id SigningIdentity) activeAt(t time.Time) bool {
+	if !id.NotBefore.IsZero() && t.Before(id.NotBefore) {
+		return false
+	}
+	if !id.NotAfter.IsZero() && !t.Before(id.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// RotatingSigner signs with the newest active identity out of a set that
+// can be replaced at runtime. While windows overlap, older identities that
+// are still active are used if the newest one fails.
+type RotatingSigner struct {
+	m          sync.RWMutex
+	identities []SigningIdentity
+}
+
+func NewRotatingSigner(identities []SigningIdentity) *RotatingSigner {
+	s := &RotatingSigner{}
+	s.SetIdentities(identities)
+	return s
+}
+
+// SetIdentities replaces the set of identities. Builds already running keep
+// signing with the identity they picked.
+func (s *RotatingSigner) SetIdentities(identities []SigningIdentity) {
+	sorted := make([]SigningIdentity, len(identities))
+	copy(sorted, identities)
+	// newest first
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].NotBefore.After(sorted[j].NotBefore)
+	})
+
+	s.m.Lock()
+	s.identities = sorted
+	s.m.Unlock()
+}
+
+func (s *RotatingSigner) SignSu3(su3File *su3.File) error {
+	s.m.RLock()
+	identities := s.identities
+	s.m.RUnlock()
+
+	now := time.Now()
+	err := fmt.Errorf("no signing identity is active at %s", now.UTC().Format(time.RFC3339))
+	for _, id := range identities {
+		if !id.activeAt(now) {
+			continue
+		}
+		if err = id.Signer.SignSu3(su3File); nil == err {
+			return nil
+		}
+		log.Printf("Unable to sign with %s: %s\n", id.Name, err)
+	}
+
+	return err
+}
+
+// HasActive reports whether any identity is active at t.
+func (s *RotatingSigner) HasActive(t time.Time) bool {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	for _, id := range s.identities {
+		if id.activeAt(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextChange returns the next time after now at which an identity becomes
+// active or expires, or the zero time if there is none.
+func (s *RotatingSigner) NextChange(now time.Time) time.Time {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	var next time.Time
+	for _, id := range s.identities {
+		for _, t := range []time.Time{id.NotBefore, id.NotAfter} {
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+
+	return next
+}
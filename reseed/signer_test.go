@@ -0,0 +1,53 @@
+package reseed
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func TestRotatingSignerGapKeepsCache(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	current := SigningIdentity{Name: "old", Signer: NewKeySigner(key, []byte("old@mail.i2p"))}
+	// the next identity only starts tomorrow
+	next := SigningIdentity{Name: "new", Signer: NewKeySigner(key, []byte("new@mail.i2p")), NotBefore: now.Add(24 * time.Hour)}
+
+	signer := NewRotatingSigner([]SigningIdentity{current, next})
+	if !signer.HasActive(now) {
+		t.Fatal("no active identity")
+	}
+
+	rs := NewReseeder(fixtureNetDb{t})
+	rs.Signer = signer
+	rs.NumRi = 2
+	rs.NumSu3 = 5
+	quit := rs.Start()
+	defer close(quit)
+
+	before, err := rs.PeerSu3Bytes(Peer("127.0.0.1"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	current.NotAfter = now
+	signer.SetIdentities([]SigningIdentity{current, next})
+	if signer.HasActive(now) {
+		t.Fatal("identity active in the gap between windows")
+	}
+	if err := rs.Rebuild(); nil == err {
+		t.Error("rebuild without an active identity succeeded")
+	}
+
+	after, err := rs.PeerSu3Bytes(Peer("127.0.0.1"))
+	if nil != err {
+		t.Fatalf("su3 cache was dropped: %s", err)
+	}
+	if string(before) != string(after) {
+		t.Error("su3 cache was replaced")
+	}
+}
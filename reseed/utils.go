@@ -1,39 +1,167 @@
 package reseed
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"net"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/MDrollette/i2p-tools/su3"
 )
 
+// KeyStore reads signer certificates from a directory laid out like the I2P
+// certificates directory: certificates/{reseed,news,plugin,router}/.
 type KeyStore struct {
 	Path string
 }
 
+var certificateDirs = map[su3.ContentType]string{
+	su3.ContentTypeReseed: "reseed",
+	su3.ContentTypeNews:   "news",
+	su3.ContentTypePlugin: "plugin",
+	su3.ContentTypeRouter: "router",
+}
+
 func (ks *KeyStore) ReseederCertificate(signer []byte) (*x509.Certificate, error) {
+	return ks.SignerCertificate(su3.ContentTypeReseed, signer)
+}
+
+// SignerCertificate loads the certificate of signer from the directory used
+// for contentType. The certificate has to be issued to signer and currently
+// valid.
+func (ks *KeyStore) SignerCertificate(contentType su3.ContentType, signer []byte) (*x509.Certificate, error) {
+	dir, ok := certificateDirs[contentType]
+	if !ok {
+		return nil, fmt.Errorf("no certificate directory for content type %s", contentType)
+	}
+
 	certFile := filepath.Base(SignerFilename(string(signer)))
-	certString, err := ioutil.ReadFile(filepath.Join(ks.Path, "reseed", certFile))
+	certString, err := ioutil.ReadFile(filepath.Join(ks.Path, dir, certFile))
 	if nil != err {
 		return nil, err
 	}
 
 	certPem, _ := pem.Decode(certString)
-	return x509.ParseCertificate(certPem.Bytes)
+	if nil == certPem {
+		return nil, fmt.Errorf("no PEM data found in %s", certFile)
+	}
+
+	cert, err := x509.ParseCertificate(certPem.Bytes)
+	if nil != err {
+		return nil, err
+	}
+
+	if cert.Subject.CommonName != string(signer) {
+		return nil, fmt.Errorf("certificate %s is issued to '%s', not '%s'", certFile, cert.Subject.CommonName, signer)
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return nil, fmt.Errorf("certificate %s is not valid before %s", certFile, cert.NotBefore)
+	}
+	if now.After(cert.NotAfter) {
+		return nil, fmt.Errorf("certificate %s expired on %s", certFile, cert.NotAfter)
+	}
+
+	if err := ks.checkRevocation(filepath.Join(ks.Path, dir), cert); nil != err {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// checkRevocation looks for CRLs (PEM or DER) in dir that were issued by
+// the issuer of cert and fails if one of them lists cert as revoked.
+func (ks *KeyStore) checkRevocation(dir string, cert *x509.Certificate) error {
+	crlFiles, err := filepath.Glob(filepath.Join(dir, "*.crl"))
+	if nil != err {
+		return err
+	}
+
+	for _, crlFile := range crlFiles {
+		crlBytes, err := ioutil.ReadFile(crlFile)
+		if nil != err {
+			return err
+		}
+		if crlPem, _ := pem.Decode(crlBytes); nil != crlPem {
+			crlBytes = crlPem.Bytes
+		}
+
+		crl, err := x509.ParseRevocationList(crlBytes)
+		if nil != err {
+			return fmt.Errorf("unable to parse CRL %s: %s", crlFile, err)
+		}
+		if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) {
+			continue
+		}
+
+		issuer, err := findIssuer(dir, cert)
+		if nil != err {
+			return err
+		}
+		// signer certificates don't carry the cRLSign key usage, so check the
+		// signature directly instead of using crl.CheckSignatureFrom
+		if err := issuer.CheckSignature(crl.SignatureAlgorithm, crl.RawTBSRevocationList, crl.Signature); nil != err {
+			return fmt.Errorf("CRL %s has an invalid signature: %s", crlFile, err)
+		}
+
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if 0 == revoked.SerialNumber.Cmp(cert.SerialNumber) {
+				return fmt.Errorf("certificate for '%s' was revoked on %s (%s)", cert.Subject.CommonName, revoked.RevocationTime, filepath.Base(crlFile))
+			}
+		}
+	}
+
+	return nil
+}
+
+// findIssuer returns the certificate that issued cert. Signer certificates
+// are self-signed, otherwise the issuer is looked up among the certificates
+// in dir.
+func findIssuer(dir string, cert *x509.Certificate) (*x509.Certificate, error) {
+	if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return cert, nil
+	}
+
+	certFiles, err := filepath.Glob(filepath.Join(dir, "*.crt"))
+	if nil != err {
+		return nil, err
+	}
+	for _, certFile := range certFiles {
+		certString, err := ioutil.ReadFile(certFile)
+		if nil != err {
+			continue
+		}
+		certPem, _ := pem.Decode(certString)
+		if nil == certPem {
+			continue
+		}
+		issuer, err := x509.ParseCertificate(certPem.Bytes)
+		if nil == err && bytes.Equal(issuer.RawSubject, cert.RawIssuer) {
+			return issuer, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no issuer certificate found for '%s'", cert.Subject.CommonName)
 }
 
 func SignerFilename(signer string) string {
 	return strings.Replace(signer, "@", "_at_", 1) + ".crt"
 }
 
-func NewTLSCertificate(host string, priv *ecdsa.PrivateKey) ([]byte, error) {
+// TLSCertificateTemplate returns the self-signed certificate template used
+// by NewTLSCertificate, for callers that want to change the subject or
+// validity before creating the certificate.
+func TLSCertificateTemplate(host string) (*x509.Certificate, error) {
 	notBefore := time.Now()
 	notAfter := notBefore.Add(5 * 365 * 24 * time.Hour)
 
@@ -43,7 +171,7 @@ func NewTLSCertificate(host string, priv *ecdsa.PrivateKey) ([]byte, error) {
 		return nil, err
 	}
 
-	template := x509.Certificate{
+	template := &x509.Certificate{
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			Organization:       []string{"I2P Anonymous Network"},
@@ -72,7 +200,16 @@ func NewTLSCertificate(host string, priv *ecdsa.PrivateKey) ([]byte, error) {
 		}
 	}
 
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	return template, nil
+}
+
+func NewTLSCertificate(host string, priv *ecdsa.PrivateKey) ([]byte, error) {
+	template, err := TLSCertificateTemplate(host)
+	if err != nil {
+		return nil, err
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
 	if err != nil {
 		return nil, err
 	}
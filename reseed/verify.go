@@ -0,0 +1,158 @@
+package reseed
+
+import (
+	"crypto"
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+var (
+	// ErrRouterInfoSignature is returned when a routerInfo is not signed by
+	// the key of its own identity.
+	ErrRouterInfoSignature = errors.New("routerInfo signature is invalid")
+	// ErrUnsupportedSigningKey is returned for signing key types routerInfos
+	// can't be verified with.
+	ErrUnsupportedSigningKey = errors.New("unsupported routerInfo signing key type")
+)
+
+// i2pDSA are the fixed DSA-SHA1 parameters all I2P keys use.
+var i2pDSA = dsa.Parameters{
+	P: hexInt("9C05B2AA960D9B97B8931963C9CC9E8C3026E9B8ED92FAD0A69CC886D5BF8015FCADAE31A0AD18FAB3F01B00A358DE237655C4964AFAA2B337E96AD316B9FB1CC564B5AEC5B69A9FF6C3E4548707FEF8503D91DD8602E867E6D35D2235C1869CE2479C3B9D5401DE04E0727FB33D6511285D4CF29538D9E3B6051F5B22CC1C93"),
+	Q: hexInt("A5DFC28FEF4CA1E286744CD8EED9D29D684046B7"),
+	G: hexInt("0C1F4D27D40093B429E962D7223824E0BBC47E7C832A39236FC683AF84889581075FF9082ED32353D4374D7301CDA1D23C431F4698599DDA02451824FF369752593647CC3DDC197DE985E43D136CDCFC6BD5409CD2F450821142A5E6F8EB1C3AB5D0484B8129FCF17BCE4F7F33321C3CB3DBB14A905E7B2B3E93BE4708CBCC82"),
+}
+
+func hexInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 16)
+	return n
+}
+
+// routerECDSA maps the ECDSA signing key types to their curve and hash.
+var routerECDSA = map[uint16]struct {
+	Curve elliptic.Curve
+	Hash  crypto.Hash
+}{
+	SigningKeyECDSASHA256P256: {elliptic.P256(), crypto.SHA256},
+	SigningKeyECDSASHA384P384: {elliptic.P384(), crypto.SHA384},
+	SigningKeyECDSASHA512P521: {elliptic.P521(), crypto.SHA512},
+}
+
+// routerRSAHash maps the RSA signing key types to their hash.
+var routerRSAHash = map[uint16]crypto.Hash{
+	SigningKeyRSASHA2562048: crypto.SHA256,
+	SigningKeyRSASHA3843072: crypto.SHA384,
+	SigningKeyRSASHA5124096: crypto.SHA512,
+}
+
+// VerifySignature checks that ri is signed by the signing key of its own
+// identity.
+func (ri *RouterInfo) VerifySignature() error {
+	data, err := ri.signedBytes()
+	if nil != err {
+		return err
+	}
+
+	pub, err := ri.Identity.SigningPublicKey()
+	if nil != err {
+		return fmt.Errorf("%w: %s", ErrUnsupportedSigningKey, err)
+	}
+
+	sigType := ri.Identity.SigningKeyType()
+	sig := ri.Signature
+	if lengths, ok := signingKeyLengths[sigType]; !ok || len(sig) != lengths.Signature {
+		return ErrRouterInfoSignature
+	}
+
+	var valid bool
+	switch sigType {
+	case SigningKeyDSASHA1:
+		digest := sha1.Sum(data)
+		key := dsa.PublicKey{Parameters: i2pDSA, Y: new(big.Int).SetBytes(pub)}
+		r := new(big.Int).SetBytes(sig[:20])
+		s := new(big.Int).SetBytes(sig[20:])
+		valid = dsa.Verify(&key, digest[:], r, s)
+	case SigningKeyECDSASHA256P256, SigningKeyECDSASHA384P384, SigningKeyECDSASHA512P521:
+		params := routerECDSA[sigType]
+		size := len(pub) / 2
+		key := ecdsa.PublicKey{
+			Curve: params.Curve,
+			X:     new(big.Int).SetBytes(pub[:size]),
+			Y:     new(big.Int).SetBytes(pub[size:]),
+		}
+		if !params.Curve.IsOnCurve(key.X, key.Y) {
+			return ErrRouterInfoSignature
+		}
+		h := params.Hash.New()
+		h.Write(data)
+		r := new(big.Int).SetBytes(sig[:len(sig)/2])
+		s := new(big.Int).SetBytes(sig[len(sig)/2:])
+		valid = ecdsa.Verify(&key, h.Sum(nil), r, s)
+	case SigningKeyRSASHA2562048, SigningKeyRSASHA3843072, SigningKeyRSASHA5124096:
+		hash := routerRSAHash[sigType]
+		key := rsa.PublicKey{N: new(big.Int).SetBytes(pub), E: 65537}
+		h := hash.New()
+		h.Write(data)
+		valid = nil == rsa.VerifyPKCS1v15(&key, hash, h.Sum(nil), sig)
+	case SigningKeyEd25519:
+		valid = ed25519.Verify(ed25519.PublicKey(pub), data, sig)
+	case SigningKeyEd25519ph:
+		// signed like su3 files, Ed25519 over the SHA-512 of the data
+		digest := sha512.Sum512(data)
+		valid = ed25519.Verify(ed25519.PublicKey(pub), digest[:], sig)
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedSigningKey, sigType)
+	}
+
+	if !valid {
+		return ErrRouterInfoSignature
+	}
+	return nil
+}
+
+// verifyRouterInfos keeps the routerInfos of ris that are correctly signed,
+// logging how many were rejected and why. Those the NetDbProvider didn't
+// parse yet are parsed first.
+func verifyRouterInfos(ris []routerInfo) []routerInfo {
+	var (
+		valid                           []routerInfo
+		unparsable, badSig, unsupported int
+	)
+	for _, ri := range ris {
+		if nil == ri.Info {
+			info, err := ParseRouterInfo(ri.Data)
+			if nil != err {
+				unparsable++
+				continue
+			}
+			ri.Info = info
+		}
+
+		err := ri.Info.VerifySignature()
+		switch {
+		case errors.Is(err, ErrUnsupportedSigningKey):
+			unsupported++
+			continue
+		case nil != err:
+			badSig++
+			continue
+		}
+
+		valid = append(valid, ri)
+	}
+
+	if rejected := len(ris) - len(valid); rejected > 0 {
+		log.Printf("Rejected %d of %d routerInfos: %d unparsable, %d invalid signatures, %d unsupported signing keys\n",
+			rejected, len(ris), unparsable, badSig, unsupported)
+	}
+
+	return valid
+}
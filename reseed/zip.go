@@ -54,7 +54,7 @@ func uzipSeeds(c []byte) ([]routerInfo, error) {
 			return nil, err
 		}
 
-		seeds = append(seeds, routerInfo{Name: f.Name, Data: data})
+		seeds = append(seeds, routerInfo{Name: f.Name, ModTime: f.Modified, Data: data})
 	}
 
 	return seeds, nil
@@ -4,12 +4,15 @@ import (
 	"crypto"
 	"crypto/dsa"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 )
@@ -20,29 +23,10 @@ type dsaSignature struct {
 
 type ecdsaSignature dsaSignature
 
-func checkSignature(c *x509.Certificate, algo x509.SignatureAlgorithm, signed, signature []byte) (err error) {
-	var hashType crypto.Hash
-
-	switch algo {
-	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
-		hashType = crypto.SHA1
-	case x509.SHA256WithRSA, x509.DSAWithSHA256, x509.ECDSAWithSHA256:
-		hashType = crypto.SHA256
-	case x509.SHA384WithRSA, x509.ECDSAWithSHA384:
-		hashType = crypto.SHA384
-	case x509.SHA512WithRSA, x509.ECDSAWithSHA512:
-		hashType = crypto.SHA512
-	default:
-		return x509.ErrUnsupportedAlgorithm
-	}
-
+func checkSignature(c *x509.Certificate, hashType crypto.Hash, digest, signature []byte) (err error) {
 	if !hashType.Available() {
 		return x509.ErrUnsupportedAlgorithm
 	}
-	h := hashType.New()
-
-	h.Write(signed)
-	digest := h.Sum(nil)
 
 	switch pub := c.PublicKey.(type) {
 	case *rsa.PublicKey:
@@ -62,7 +46,11 @@ func checkSignature(c *x509.Certificate, algo x509.SignatureAlgorithm, signed, s
 		return
 	case *ecdsa.PublicKey:
 		ecdsaSig := new(ecdsaSignature)
-		if _, err := asn1.Unmarshal(signature, ecdsaSig); err != nil {
+		if size := ecdsaKeySize(pub); len(signature) == 2*size {
+			// I2P uses a fixed width r||s encoding
+			ecdsaSig.R = new(big.Int).SetBytes(signature[:size])
+			ecdsaSig.S = new(big.Int).SetBytes(signature[size:])
+		} else if _, err := asn1.Unmarshal(signature, ecdsaSig); err != nil {
 			return err
 		}
 		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
@@ -72,11 +60,157 @@ func checkSignature(c *x509.Certificate, algo x509.SignatureAlgorithm, signed, s
 			return errors.New("x509: ECDSA verification failure")
 		}
 		return
+	case ed25519.PublicKey:
+		// Ed25519ph in I2P signs the SHA-512 digest as a plain message
+		if !ed25519.Verify(pub, digest, signature) {
+			return errors.New("x509: Ed25519 verification failure")
+		}
+		return
 	}
 	return x509.ErrUnsupportedAlgorithm
 }
 
-func NewSigningCertificate(signerID string, privateKey *rsa.PrivateKey) ([]byte, error) {
+// SignatureLength returns the length of a signature made with pub for the
+// given signature type. The su3 spec pairs every type with one key size, so
+// an error is returned if pub doesn't match it.
+func SignatureLength(sigType uint16, pub crypto.PublicKey) (int, error) {
+	switch sigType {
+	case SigTypeDSA:
+		if k, ok := pub.(*dsa.PublicKey); ok && 1024 == k.P.BitLen() {
+			return 40, nil
+		}
+	case SigTypeECDSAWithSHA256, SigTypeECDSAWithSHA384, SigTypeECDSAWithSHA512:
+		if k, ok := pub.(*ecdsa.PublicKey); ok && k.Curve == sigTypeCurves[sigType] {
+			return 2 * ecdsaKeySize(k), nil
+		}
+	case SigTypeRSAWithSHA256, SigTypeRSAWithSHA384, SigTypeRSAWithSHA512:
+		if k, ok := pub.(*rsa.PublicKey); ok && k.N.BitLen() == sigTypeRSABits[sigType] {
+			return k.Size(), nil
+		}
+	case SigTypeEdDSASHA512Ed25519ph:
+		if _, ok := pub.(ed25519.PublicKey); ok {
+			return ed25519.SignatureSize, nil
+		}
+	default:
+		return 0, fmt.Errorf("%w: %d", ErrUnknownSignatureType, sigType)
+	}
+
+	return 0, fmt.Errorf("%s key does not match signature type %d", keyDescription(pub), sigType)
+}
+
+// SignatureTypeForKey returns the signature type the su3 spec pairs with pub.
+func SignatureTypeForKey(pub crypto.PublicKey) (uint16, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		for sigType, bits := range sigTypeRSABits {
+			if k.N.BitLen() == bits {
+				return sigType, nil
+			}
+		}
+	case *ecdsa.PublicKey:
+		for sigType, curve := range sigTypeCurves {
+			if k.Curve == curve {
+				return sigType, nil
+			}
+		}
+	case ed25519.PublicKey:
+		return SigTypeEdDSASHA512Ed25519ph, nil
+	}
+
+	return 0, fmt.Errorf("no su3 signature type for %s key", keyDescription(pub))
+}
+
+var (
+	sigTypeCurves = map[uint16]elliptic.Curve{
+		SigTypeECDSAWithSHA256: elliptic.P256(),
+		SigTypeECDSAWithSHA384: elliptic.P384(),
+		SigTypeECDSAWithSHA512: elliptic.P521(),
+	}
+	sigTypeRSABits = map[uint16]int{
+		SigTypeRSAWithSHA256: 2048,
+		SigTypeRSAWithSHA384: 3072,
+		SigTypeRSAWithSHA512: 4096,
+	}
+)
+
+func keyDescription(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case *dsa.PublicKey:
+		return fmt.Sprintf("DSA-%d", k.P.BitLen())
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+func signDigest(signer crypto.Signer, sigType uint16, digest []byte) ([]byte, error) {
+	hashType, err := sigTypeHash(sigType)
+	if nil != err {
+		return nil, err
+	}
+
+	switch sigType {
+	case SigTypeRSAWithSHA256, SigTypeRSAWithSHA384, SigTypeRSAWithSHA512:
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("signature type %d requires an RSA key", sigType)
+		}
+		// the digest is already hashed, so we force a 0 here
+		return signer.Sign(rand.Reader, digest, crypto.Hash(0))
+	case SigTypeECDSAWithSHA256, SigTypeECDSAWithSHA384, SigTypeECDSAWithSHA512:
+		pub, ok := signer.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("signature type %d requires an ECDSA key", sigType)
+		}
+		der, err := signer.Sign(rand.Reader, digest, hashType)
+		if nil != err {
+			return nil, err
+		}
+		return ecdsaRawSignature(pub, der)
+	case SigTypeEdDSASHA512Ed25519ph:
+		if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("signature type %d requires an Ed25519 key", sigType)
+		}
+		// the digest is signed as the message, without the RFC 8032 prehash domain
+		return signer.Sign(rand.Reader, digest, crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("signing is not supported for signature type %d", sigType)
+	}
+}
+
+// ecdsaRawSignature converts an ASN.1 ECDSA signature to the fixed width
+// r||s encoding used by I2P.
+func ecdsaRawSignature(pub *ecdsa.PublicKey, der []byte) ([]byte, error) {
+	ecdsaSig := new(ecdsaSignature)
+	if _, err := asn1.Unmarshal(der, ecdsaSig); err != nil {
+		return nil, err
+	}
+
+	size := ecdsaKeySize(pub)
+	if ecdsaSig.R.BitLen() > 8*size || ecdsaSig.S.BitLen() > 8*size {
+		return nil, errors.New("ECDSA signature does not fit the curve size")
+	}
+
+	raw := make([]byte, 2*size)
+	ecdsaSig.R.FillBytes(raw[:size])
+	ecdsaSig.S.FillBytes(raw[size:])
+
+	return raw, nil
+}
+
+// ecdsaKeySize is the length in bytes of each half of a raw signature.
+func ecdsaKeySize(pub *ecdsa.PublicKey) int {
+	return (pub.Curve.Params().BitSize + 7) / 8
+}
+
+// SigningCertificateTemplate returns the self-signed certificate template
+// used by NewSigningCertificate, for callers that want to change the subject
+// or validity before creating the certificate.
+func SigningCertificateTemplate(signerID string) (*x509.Certificate, error) {
 	serialNumberLimit := new(big.Int).Lsh(big.NewInt(1), 128)
 	serialNumber, err := rand.Int(rand.Reader, serialNumberLimit)
 	if err != nil {
@@ -102,7 +236,16 @@ func NewSigningCertificate(signerID string, privateKey *rsa.PrivateKey) ([]byte,
 		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
 	}
 
-	publicKey := &privateKey.PublicKey
+	return template, nil
+}
+
+func NewSigningCertificate(signerID string, privateKey crypto.Signer) ([]byte, error) {
+	template, err := SigningCertificateTemplate(signerID)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := privateKey.Public()
 
 	// create a self-signed certificate. template = parent
 	var parent = template
@@ -0,0 +1,228 @@
+package su3
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+// testKeys are generated once, RSA-4096 takes a while.
+var testKeys = map[uint16]crypto.Signer{}
+
+func testKey(t *testing.T, sigType uint16) crypto.Signer {
+	if key, ok := testKeys[sigType]; ok {
+		return key
+	}
+
+	var (
+		key crypto.Signer
+		err error
+	)
+	if curve, ok := sigTypeCurves[sigType]; ok {
+		key, err = ecdsa.GenerateKey(curve, rand.Reader)
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, sigTypeRSABits[sigType])
+	}
+	if nil != err {
+		t.Fatal(err)
+	}
+	testKeys[sigType] = key
+	return key
+}
+
+func testCertificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	der, err := NewSigningCertificate("test@mail.i2p", key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// signedFile signs a news su3 with key and reads it back.
+func signedFile(t *testing.T, sigType uint16, key crypto.Signer) (*File, []byte) {
+	f := New()
+	f.SignatureType = sigType
+	f.FileType = FileTypeXML
+	f.ContentType = ContentTypeNews
+	f.SignerID = []byte("test@mail.i2p")
+	f.Content = []byte("<feed>su3 test</feed>\n")
+	if err := f.Sign(key); nil != err {
+		t.Fatalf("type %d: %s", sigType, err)
+	}
+
+	data, err := f.MarshalBinary()
+	if nil != err {
+		t.Fatal(err)
+	}
+	read := new(File)
+	if err := read.UnmarshalBinary(data); nil != err {
+		t.Fatalf("type %d: %s", sigType, err)
+	}
+	return read, data
+}
+
+var ecdsaAndRSATypes = []uint16{
+	SigTypeECDSAWithSHA256, SigTypeECDSAWithSHA384, SigTypeECDSAWithSHA512,
+	SigTypeRSAWithSHA256, SigTypeRSAWithSHA384, SigTypeRSAWithSHA512,
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, sigType := range ecdsaAndRSATypes {
+		key := testKey(t, sigType)
+		f, _ := signedFile(t, sigType, key)
+
+		want, _ := SignatureLength(sigType, key.Public())
+		if len(f.Signature) != want {
+			t.Errorf("type %d: signature of %d bytes, want %d", sigType, len(f.Signature), want)
+		}
+		if err := f.VerifySignature(testCertificate(t, key)); nil != err {
+			t.Errorf("type %d: %s", sigType, err)
+		}
+	}
+}
+
+func TestVerifyTamperedBody(t *testing.T) {
+	for _, sigType := range ecdsaAndRSATypes {
+		key := testKey(t, sigType)
+		f, data := signedFile(t, sigType, key)
+
+		// flip a bit of the content, which ends right before the signature
+		data[len(data)-len(f.Signature)-2] ^= 1
+		tampered := new(File)
+		if err := tampered.UnmarshalBinary(data); nil != err {
+			t.Fatal(err)
+		}
+		if err := tampered.VerifySignature(testCertificate(t, key)); nil == err {
+			t.Errorf("type %d: tampered su3 verified", sigType)
+		}
+	}
+}
+
+func TestECDSARawSignature(t *testing.T) {
+	key := testKey(t, SigTypeECDSAWithSHA384).(*ecdsa.PrivateKey)
+
+	// r and s shorter than the curve size are left padded with zeros
+	der, err := asn1.Marshal(ecdsaSignature{R: big.NewInt(0x0102), S: big.NewInt(0x03)})
+	if nil != err {
+		t.Fatal(err)
+	}
+	raw, err := ecdsaRawSignature(&key.PublicKey, der)
+	if nil != err {
+		t.Fatal(err)
+	}
+	want := make([]byte, 96)
+	want[46], want[47], want[95] = 0x01, 0x02, 0x03
+	if string(raw) != string(want) {
+		t.Errorf("raw signature %x, want %x", raw, want)
+	}
+
+	// values wider than the curve don't fit
+	der, _ = asn1.Marshal(ecdsaSignature{R: new(big.Int).Lsh(big.NewInt(1), 384), S: big.NewInt(1)})
+	if _, err := ecdsaRawSignature(&key.PublicKey, der); nil == err {
+		t.Error("converted an r wider than the curve")
+	}
+}
+
+func TestVerifyASN1ECDSASignature(t *testing.T) {
+	key := testKey(t, SigTypeECDSAWithSHA384).(*ecdsa.PrivateKey)
+	cert := testCertificate(t, key)
+
+	digest := sha512.Sum384([]byte("su3 body"))
+	der, err := key.Sign(rand.Reader, digest[:], crypto.SHA384)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := checkSignature(cert, crypto.SHA384, digest[:], der); nil != err {
+		t.Errorf("ASN.1 signature: %s", err)
+	}
+
+	raw, err := ecdsaRawSignature(&key.PublicKey, der)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := checkSignature(cert, crypto.SHA384, digest[:], raw); nil != err {
+		t.Errorf("raw signature: %s", err)
+	}
+	raw[0] ^= 1
+	if err := checkSignature(cert, crypto.SHA384, digest[:], raw); nil == err {
+		t.Error("tampered raw signature verified")
+	}
+}
+
+// TestVerifyP384Reference checks testdata/p384.su3, which make_p384.sh
+// writes with openssl instead of this package.
+func TestVerifyP384Reference(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "p384.su3"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	certPem, err := ioutil.ReadFile(filepath.Join("testdata", "p384.crt"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(certPem)
+	if nil == block {
+		t.Fatal("no certificate in p384.crt")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	f := new(File)
+	if err := f.UnmarshalBinary(data); nil != err {
+		t.Fatal(err)
+	}
+	if f.SignatureType != SigTypeECDSAWithSHA384 || f.ContentType != ContentTypeNews || f.FileType != FileTypeXML {
+		t.Errorf("read type %d, %s, %s", f.SignatureType, f.ContentType, f.FileType)
+	}
+	if string(f.Content) != "<feed>su3 test</feed>\n" {
+		t.Errorf("read content %q", f.Content)
+	}
+	if err := f.VerifySignature(cert); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestSignAndVerifyEd25519ph(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	f, data := signedFile(t, SigTypeEdDSASHA512Ed25519ph, key)
+
+	// I2P signs the SHA-512 of the body as a plain Ed25519 message, which
+	// is deterministic
+	digest := sha512.Sum512(f.BodyBytes())
+	if want := ed25519.Sign(key, digest[:]); string(f.Signature) != string(want) {
+		t.Error("signature is not Ed25519 over the SHA-512 of the body")
+	}
+
+	cert := testCertificate(t, key)
+	if err := f.VerifySignature(cert); nil != err {
+		t.Error(err)
+	}
+
+	data[len(data)-len(f.Signature)-2] ^= 1
+	tampered := new(File)
+	if err := tampered.UnmarshalBinary(data); nil != err {
+		t.Fatal(err)
+	}
+	if err := tampered.VerifySignature(cert); nil == err {
+		t.Error("tampered su3 verified")
+	}
+}
@@ -0,0 +1,56 @@
+package su3
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrBadMagic is returned when a file does not start with "I2Psu3".
+	ErrBadMagic = errors.New("su3: invalid magic bytes")
+	// ErrUnsupportedFormat is returned for a format version other than 0.
+	ErrUnsupportedFormat = errors.New("su3: unsupported format version")
+	// ErrUnknownSignatureType is returned for a signature type this package
+	// can't handle.
+	ErrUnknownSignatureType = errors.New("su3: unknown signature type")
+	// ErrTruncated is returned when the file ends before the lengths given
+	// in the header are satisfied.
+	ErrTruncated = errors.New("su3: truncated file")
+	// ErrSignatureLength is returned when the signature length doesn't match
+	// the signature type.
+	ErrSignatureLength = errors.New("su3: signature length does not match signature type")
+	// ErrVersionLength is returned when the version field is shorter than
+	// the spec requires.
+	ErrVersionLength = errors.New("su3: version too short")
+	// ErrVersionNotTimestamp is returned by File.Timestamp when the version
+	// is not a unix time.
+	ErrVersionNotTimestamp = errors.New("su3: version is not a timestamp")
+	// ErrFieldTooLong is returned when the version or signer ID doesn't fit
+	// the one byte length in the header.
+	ErrFieldTooLong = errors.New("su3: field longer than 255 bytes")
+	// ErrMissingSignerID is returned when the signer ID is empty.
+	ErrMissingSignerID = errors.New("su3: missing signer ID")
+	// ErrContentTooLarge is returned when the content exceeds the
+	// configured Limits.
+	ErrContentTooLarge = errors.New("su3: content exceeds size limit")
+)
+
+// Limits bounds what a Reader accepts before it trusts the lengths in a
+// header.
+type Limits struct {
+	MaxContentLength uint64
+}
+
+// DefaultLimits is used by NewReader, File.UnmarshalBinary and
+// File.Validate. It is large enough for router updates and plugins.
+var DefaultLimits = Limits{
+	MaxContentLength: 128 << 20,
+}
+
+// truncated turns the EOF errors of a short read into ErrTruncated.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
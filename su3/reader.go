@@ -0,0 +1,159 @@
+package su3
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+)
+
+// Reader parses an su3 file from a stream without holding the content in
+// memory. The header is read by NewReader, the content is read through Read
+// and the signed bytes are hashed as they go by.
+type Reader struct {
+	// Header holds the parsed header fields. Content and Signature are not
+	// populated, use Read and Signature instead.
+	Header *File
+
+	r               io.Reader
+	hash            hash.Hash
+	contentLength   uint64
+	remaining       uint64
+	signatureLength uint16
+	signature       []byte
+}
+
+// NewReader reads the su3 header, version and signer ID from r using
+// DefaultLimits.
+func NewReader(r io.Reader) (*Reader, error) {
+	return NewReaderWithLimits(r, DefaultLimits)
+}
+
+// NewReaderWithLimits reads the su3 header, version and signer ID from r and
+// rejects headers that are malformed or exceed limits.
+func NewReaderWithLimits(r io.Reader, limits Limits) (*Reader, error) {
+	// keep the header as read, the signature covers the reserved bytes too
+	raw := make([]byte, binary.Size(header{}))
+	if _, err := io.ReadFull(r, raw); nil != err {
+		return nil, truncated(err)
+	}
+	var hdr header
+	binary.Read(bytes.NewReader(raw), binary.BigEndian, &hdr)
+
+	if magicBytes != string(hdr.Magic[:]) {
+		return nil, ErrBadMagic
+	}
+	if 0 != hdr.Format {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedFormat, hdr.Format)
+	}
+	if hdr.ContentLength > limits.MaxContentLength {
+		return nil, fmt.Errorf("%w: %d > %d bytes", ErrContentTooLarge, hdr.ContentLength, limits.MaxContentLength)
+	}
+
+	f := &File{
+		Format:        hdr.Format,
+		SignatureType: hdr.SignatureType,
+		FileType:      hdr.FileType,
+		ContentType:   hdr.ContentType,
+		Version:       make([]byte, hdr.VersionLength),
+		SignerID:      make([]byte, hdr.SignerIDLength),
+	}
+
+	if err := f.checkSignatureLength(hdr.SignatureLength); nil != err {
+		return nil, err
+	}
+
+	if _, err := io.ReadFull(r, f.Version); nil != err {
+		return nil, truncated(err)
+	}
+	if _, err := io.ReadFull(r, f.SignerID); nil != err {
+		return nil, truncated(err)
+	}
+
+	sr := &Reader{
+		Header:          f,
+		r:               r,
+		contentLength:   hdr.ContentLength,
+		remaining:       hdr.ContentLength,
+		signatureLength: hdr.SignatureLength,
+	}
+
+	hashType, err := sigTypeHash(f.SignatureType)
+	if nil != err {
+		return nil, err
+	}
+
+	// the signature covers everything before it, so start with the header
+	sr.hash = hashType.New()
+	sr.hash.Write(raw)
+	sr.hash.Write(f.Version)
+	sr.hash.Write(f.SignerID)
+
+	return sr, nil
+}
+
+// ContentLength returns the content length declared in the header.
+func (r *Reader) ContentLength() uint64 {
+	return r.contentLength
+}
+
+// Read reads from the content of the su3 file. It returns io.EOF at the end
+// of the content and ErrTruncated if the stream ends before that.
+func (r *Reader) Read(p []byte) (int, error) {
+	if 0 == r.remaining {
+		return 0, io.EOF
+	}
+
+	if uint64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.r.Read(p)
+	r.remaining -= uint64(n)
+	r.hash.Write(p[:n])
+
+	if io.EOF == err && r.remaining > 0 {
+		err = ErrTruncated
+	}
+
+	return n, err
+}
+
+// Signature discards any unread content and returns the signature that
+// follows it.
+func (r *Reader) Signature() ([]byte, error) {
+	if nil != r.signature {
+		return r.signature, nil
+	}
+
+	if _, err := io.Copy(ioutil.Discard, r); nil != err {
+		return nil, err
+	}
+
+	sig := make([]byte, r.signatureLength)
+	if _, err := io.ReadFull(r.r, sig); nil != err {
+		return nil, truncated(err)
+	}
+	r.signature = sig
+
+	return sig, nil
+}
+
+// VerifySignature reads the rest of the file and checks the signature
+// against cert.
+func (r *Reader) VerifySignature(cert *x509.Certificate) error {
+	sig, err := r.Signature()
+	if nil != err {
+		return err
+	}
+
+	if _, err := SignatureLength(r.Header.SignatureType, cert.PublicKey); nil != err {
+		return err
+	}
+
+	hashType, _ := sigTypeHash(r.Header.SignatureType)
+	return checkSignature(cert, hashType, r.hash.Sum(nil), sig)
+}
@@ -0,0 +1,72 @@
+package su3
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	key := testEd25519Key(t)
+	content := bytes.Repeat([]byte("<feed>su3 test</feed>\n"), 1000)
+	data := writeStream(t, testFile(content), key, 4096)
+
+	r, err := NewReader(bytes.NewReader(data))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if r.Header.ContentType != ContentTypeNews || r.Header.FileType != FileTypeXML || string(r.Header.SignerID) != "test@mail.i2p" {
+		t.Errorf("read header %s", r.Header)
+	}
+	if r.ContentLength() != uint64(len(content)) {
+		t.Errorf("content length %d, want %d", r.ContentLength(), len(content))
+	}
+
+	read, err := ioutil.ReadAll(r)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, content) {
+		t.Error("read content differs")
+	}
+	if err := r.VerifySignature(testCertificate(t, key)); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestReaderTamperedContent(t *testing.T) {
+	key := testEd25519Key(t)
+	data := writeStream(t, testFile([]byte("<feed>su3 test</feed>\n")), key, 4096)
+	data[len(data)-ed25519.SignatureSize-2] ^= 1
+
+	r, err := NewReader(bytes.NewReader(data))
+	if nil != err {
+		t.Fatal(err)
+	}
+	// VerifySignature reads the content it wasn't given
+	if err := r.VerifySignature(testCertificate(t, key)); nil == err {
+		t.Error("tampered su3 verified")
+	}
+}
+
+func TestReaderTruncated(t *testing.T) {
+	key := testEd25519Key(t)
+	data := writeStream(t, testFile([]byte("<feed>su3 test</feed>\n")), key, 4096)
+	headerLength := 40 + 16 + len("test@mail.i2p")
+
+	// in the header, the version, the signer ID, the content and the signature
+	for _, n := range []int{0, 20, 45, headerLength - 1, headerLength + 5, len(data) - 1} {
+		r, err := NewReader(bytes.NewReader(data[:n]))
+		if nil == err {
+			_, err = ioutil.ReadAll(r)
+		}
+		if nil == err {
+			_, err = r.Signature()
+		}
+		if !errors.Is(err, ErrTruncated) {
+			t.Errorf("reading %d of %d bytes gave %v", n, len(data), err)
+		}
+	}
+}
@@ -3,17 +3,18 @@ package su3
 import (
 	"bytes"
 	"crypto"
-	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"encoding/binary"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"strconv"
 	"time"
 )
 
 const (
 	minVersionLength = 16
+	maxFieldLength   = 255
 
 	SigTypeDSA             = uint16(0)
 	SigTypeECDSAWithSHA256 = uint16(1)
@@ -23,16 +24,22 @@ const (
 	SigTypeRSAWithSHA384   = uint16(5)
 	SigTypeRSAWithSHA512   = uint16(6)
 
-	ContentTypeUnknown = uint8(0)
-	ContentTypeRouter  = uint8(1)
-	ContentTypePlugin  = uint8(2)
-	ContentTypeReseed  = uint8(3)
-	ContentTypeNews    = uint8(4)
+	SigTypeEdDSASHA512Ed25519ph = uint16(8)
 
-	FileTypeZIP   = uint8(0)
-	FileTypeXML   = uint8(1)
-	FileTypeHTML  = uint8(2)
-	FileTypeXMLGZ = uint8(3)
+	ContentTypeUnknown   = ContentType(0)
+	ContentTypeRouter    = ContentType(1)
+	ContentTypePlugin    = ContentType(2)
+	ContentTypeReseed    = ContentType(3)
+	ContentTypeNews      = ContentType(4)
+	ContentTypeBlocklist = ContentType(5)
+
+	FileTypeZIP   = FileType(0)
+	FileTypeXML   = FileType(1)
+	FileTypeHTML  = FileType(2)
+	FileTypeXMLGZ = FileType(3)
+	FileTypeTXTGZ = FileType(4)
+	FileTypeDMG   = FileType(5)
+	FileTypeEXE   = FileType(6)
 
 	magicBytes = "I2Psu3"
 )
@@ -40,13 +47,15 @@ const (
 type File struct {
 	Format        uint8
 	SignatureType uint16
-	FileType      uint8
-	ContentType   uint8
-
-	Version     []byte
-	SignerID    []byte
-	Content     []byte
-	Signature   []byte
+	FileType      FileType
+	ContentType   ContentType
+
+	Version   []byte
+	SignerID  []byte
+	Content   []byte
+	Signature []byte
+	// SignedBytes are the bytes the signature covers as they were read or
+	// signed. VerifySignature checks them instead of re-encoding the fields.
 	SignedBytes []byte
 }
 
@@ -57,91 +66,88 @@ func New() *File {
 	}
 }
 
-func (s *File) Sign(privkey *rsa.PrivateKey) error {
-	var hashType crypto.Hash
-	switch s.SignatureType {
+// header is the fixed size part of an su3 file. The blank fields are unused
+// bytes in the format and are always zero.
+type header struct {
+	Magic           [6]byte
+	_               uint8
+	Format          uint8
+	SignatureType   uint16
+	SignatureLength uint16
+	_               uint8
+	VersionLength   uint8
+	_               uint8
+	SignerIDLength  uint8
+	ContentLength   uint64
+	_               uint8
+	FileType        FileType
+	_               uint8
+	ContentType     ContentType
+	_               [12]byte
+}
+
+func sigTypeHash(sigType uint16) (crypto.Hash, error) {
+	switch sigType {
 	case SigTypeDSA:
-		hashType = crypto.SHA1
+		return crypto.SHA1, nil
 	case SigTypeECDSAWithSHA256, SigTypeRSAWithSHA256:
-		hashType = crypto.SHA256
+		return crypto.SHA256, nil
 	case SigTypeECDSAWithSHA384, SigTypeRSAWithSHA384:
-		hashType = crypto.SHA384
-	case SigTypeECDSAWithSHA512, SigTypeRSAWithSHA512:
-		hashType = crypto.SHA512
+		return crypto.SHA384, nil
+	case SigTypeECDSAWithSHA512, SigTypeRSAWithSHA512, SigTypeEdDSASHA512Ed25519ph:
+		return crypto.SHA512, nil
 	default:
-		return fmt.Errorf("unknown signature type: %d", s.SignatureType)
+		return 0, fmt.Errorf("%w: %d", ErrUnknownSignatureType, sigType)
 	}
+}
 
+// Sign signs the file with signer. The hash and signature encoding are
+// picked from SignatureType, so the key has to match it.
+func (s *File) Sign(signer crypto.Signer) error {
+	hashType, err := sigTypeHash(s.SignatureType)
+	if nil != err {
+		return err
+	}
+
+	sigLength, err := SignatureLength(s.SignatureType, signer.Public())
+	if nil != err {
+		return err
+	}
+	if err := s.checkFieldLengths(); nil != err {
+		return err
+	}
+
+	body := s.BodyBytes()
 	h := hashType.New()
-	h.Write(s.BodyBytes())
-	digest := h.Sum(nil)
+	h.Write(body)
 
-	sig, err := rsa.SignPKCS1v15(rand.Reader, privkey, 0, digest)
+	sig, err := signDigest(signer, s.SignatureType, h.Sum(nil))
 	if nil != err {
 		return err
 	}
+	if len(sig) != sigLength {
+		return fmt.Errorf("%w: signer produced %d bytes, want %d", ErrSignatureLength, len(sig), sigLength)
+	}
 
 	s.Signature = sig
+	s.SignedBytes = body
 
 	return nil
 }
 
 func (s *File) BodyBytes() []byte {
-	var (
-		buf = new(bytes.Buffer)
-
-		skip    [1]byte
-		bigSkip [12]byte
-
-		versionLength   = uint8(len(s.Version))
-		signatureLength = uint16(512)
-		signerIDLength  = uint8(len(s.SignerID))
-		contentLength   = uint64(len(s.Content))
-	)
-
-	// determine sig length based on type
-	switch s.SignatureType {
-	case SigTypeDSA:
-		signatureLength = uint16(40)
-	case SigTypeECDSAWithSHA256, SigTypeRSAWithSHA256:
-		signatureLength = uint16(256)
-	case SigTypeECDSAWithSHA384, SigTypeRSAWithSHA384:
-		signatureLength = uint16(384)
-	case SigTypeECDSAWithSHA512, SigTypeRSAWithSHA512:
-		signatureLength = uint16(512)
-	}
+	buf := new(bytes.Buffer)
 
-	// pad the version field
-	if len(s.Version) < minVersionLength {
-		minBytes := make([]byte, minVersionLength)
-		copy(minBytes, s.Version)
-		s.Version = minBytes
-		versionLength = uint8(len(s.Version))
-	}
-
-	binary.Write(buf, binary.BigEndian, []byte(magicBytes))
-	binary.Write(buf, binary.BigEndian, skip)
-	binary.Write(buf, binary.BigEndian, s.Format)
-	binary.Write(buf, binary.BigEndian, s.SignatureType)
-	binary.Write(buf, binary.BigEndian, signatureLength)
-	binary.Write(buf, binary.BigEndian, skip)
-	binary.Write(buf, binary.BigEndian, versionLength)
-	binary.Write(buf, binary.BigEndian, skip)
-	binary.Write(buf, binary.BigEndian, signerIDLength)
-	binary.Write(buf, binary.BigEndian, contentLength)
-	binary.Write(buf, binary.BigEndian, skip)
-	binary.Write(buf, binary.BigEndian, s.FileType)
-	binary.Write(buf, binary.BigEndian, skip)
-	binary.Write(buf, binary.BigEndian, s.ContentType)
-	binary.Write(buf, binary.BigEndian, bigSkip)
-	binary.Write(buf, binary.BigEndian, s.Version)
-	binary.Write(buf, binary.BigEndian, s.SignerID)
+	s.writeHeader(buf, uint64(len(s.Content)))
 	binary.Write(buf, binary.BigEndian, s.Content)
 
 	return buf.Bytes()
 }
 
 func (s *File) MarshalBinary() ([]byte, error) {
+	if err := s.checkFieldLengths(); nil != err {
+		return nil, err
+	}
 	buf := bytes.NewBuffer(s.BodyBytes())
 
 	// append the signature
@@ -151,70 +157,164 @@ func (s *File) MarshalBinary() ([]byte, error) {
 }
 
 func (s *File) UnmarshalBinary(data []byte) error {
-	var (
-		r = bytes.NewReader(data)
-
-		magic   = []byte(magicBytes)
-		skip    [1]byte
-		bigSkip [12]byte
-
-		signatureLength uint16
-		versionLength   uint8
-		signerIDLength  uint8
-		contentLength   uint64
-	)
-
-	binary.Read(r, binary.BigEndian, &magic)
-	binary.Read(r, binary.BigEndian, &skip)
-	binary.Read(r, binary.BigEndian, &s.Format)
-	binary.Read(r, binary.BigEndian, &s.SignatureType)
-	binary.Read(r, binary.BigEndian, &signatureLength)
-	binary.Read(r, binary.BigEndian, &skip)
-	binary.Read(r, binary.BigEndian, &versionLength)
-	binary.Read(r, binary.BigEndian, &skip)
-	binary.Read(r, binary.BigEndian, &signerIDLength)
-	binary.Read(r, binary.BigEndian, &contentLength)
-	binary.Read(r, binary.BigEndian, &skip)
-	binary.Read(r, binary.BigEndian, &s.FileType)
-	binary.Read(r, binary.BigEndian, &skip)
-	binary.Read(r, binary.BigEndian, &s.ContentType)
-	binary.Read(r, binary.BigEndian, &bigSkip)
-
-	s.Version = make([]byte, versionLength)
-	s.SignerID = make([]byte, signerIDLength)
-	s.Content = make([]byte, contentLength)
-	s.Signature = make([]byte, signatureLength)
-
-	binary.Read(r, binary.BigEndian, &s.Version)
-	binary.Read(r, binary.BigEndian, &s.SignerID)
-	binary.Read(r, binary.BigEndian, &s.Content)
-	binary.Read(r, binary.BigEndian, &s.Signature)
+	br := bytes.NewReader(data)
+	r, err := NewReader(br)
+	if nil != err {
+		return err
+	}
+
+	content, err := ioutil.ReadAll(r)
+	if nil != err {
+		return err
+	}
+
+	signature, err := r.Signature()
+	if nil != err {
+		return err
+	}
+
+	*s = *r.Header
+	s.Content = content
+	s.Signature = signature
+	s.SignedBytes = data[:len(data)-br.Len()-len(signature)]
 
 	return nil
 }
 
 func (s *File) VerifySignature(cert *x509.Certificate) error {
-	var sigAlg x509.SignatureAlgorithm
+	hashType, err := sigTypeHash(s.SignatureType)
+	if nil != err {
+		return err
+	}
+	if _, err := SignatureLength(s.SignatureType, cert.PublicKey); nil != err {
+		return err
+	}
+
+	body := s.SignedBytes
+	if nil == body {
+		body = s.BodyBytes()
+	}
+
+	h := hashType.New()
+	h.Write(body)
+
+	return checkSignature(cert, hashType, h.Sum(nil), s.Signature)
+}
+
+// Timestamp parses the version as the unix time New sets it to. Reseed and
+// news files carry such versions; router updates and plugins usually don't.
+func (s *File) Timestamp() (time.Time, error) {
+	version := string(bytes.Trim(s.Version, "\x00"))
+	secs, err := strconv.ParseInt(version, 10, 64)
+	if nil != err || secs < 0 {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrVersionNotTimestamp, version)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// Validate checks the header fields and the signature length against the
+// su3 spec and DefaultLimits. It doesn't check the signature itself.
+func (s *File) Validate() error {
+	if 0 != s.Format {
+		return fmt.Errorf("%w: %d", ErrUnsupportedFormat, s.Format)
+	}
+	if _, err := sigTypeHash(s.SignatureType); nil != err {
+		return err
+	}
+	if len(s.Version) < minVersionLength {
+		return fmt.Errorf("%w: %d < %d bytes", ErrVersionLength, len(s.Version), minVersionLength)
+	}
+	if err := s.checkFieldLengths(); nil != err {
+		return err
+	}
+	if 0 == len(bytes.Trim(s.SignerID, "\x00")) {
+		return ErrMissingSignerID
+	}
+	if uint64(len(s.Content)) > DefaultLimits.MaxContentLength {
+		return fmt.Errorf("%w: %d > %d bytes", ErrContentTooLarge, len(s.Content), DefaultLimits.MaxContentLength)
+	}
+
+	return s.checkSignatureLength(uint16(len(s.Signature)))
+}
+
+// checkSignatureLength compares a signature length with the one required by
+// the signature type.
+func (s *File) checkSignatureLength(length uint16) error {
+	if _, err := sigTypeHash(s.SignatureType); nil != err {
+		return err
+	}
+	if expected := s.signatureLength(); length != expected {
+		return fmt.Errorf("%w: got %d, want %d bytes for type %d", ErrSignatureLength, length, expected, s.SignatureType)
+	}
+	return nil
+}
+
+// checkFieldLengths makes sure the version and signer ID fit their one byte
+// lengths in the header.
+func (s *File) checkFieldLengths() error {
+	if len(s.Version) > maxFieldLength {
+		return fmt.Errorf("%w: version is %d bytes", ErrFieldTooLong, len(s.Version))
+	}
+	if len(s.SignerID) > maxFieldLength {
+		return fmt.Errorf("%w: signer ID is %d bytes", ErrFieldTooLong, len(s.SignerID))
+	}
+	return nil
+}
+
+// writeHeader writes everything that precedes the content: the fixed size
+// header followed by the version and the signer ID.
+func (s *File) writeHeader(w io.Writer, contentLength uint64) error {
+	if err := s.checkFieldLengths(); nil != err {
+		return err
+	}
+
+	// pad the version field
+	if len(s.Version) < minVersionLength {
+		minBytes := make([]byte, minVersionLength)
+		copy(minBytes, s.Version)
+		s.Version = minBytes
+	}
+
+	hdr := header{
+		Format:          s.Format,
+		SignatureType:   s.SignatureType,
+		SignatureLength: s.signatureLength(),
+		VersionLength:   uint8(len(s.Version)),
+		SignerIDLength:  uint8(len(s.SignerID)),
+		ContentLength:   contentLength,
+		FileType:        s.FileType,
+		ContentType:     s.ContentType,
+	}
+	copy(hdr.Magic[:], magicBytes)
+
+	if err := binary.Write(w, binary.BigEndian, hdr); nil != err {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, s.Version); nil != err {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, s.SignerID)
+}
+
+// signatureLength is the sig length for the key size the spec pairs with the
+// type. Sign makes sure the key actually matches, see SignatureLength.
+func (s *File) signatureLength() uint16 {
 	switch s.SignatureType {
 	case SigTypeDSA:
-		sigAlg = x509.DSAWithSHA1
-	case SigTypeECDSAWithSHA256:
-		sigAlg = x509.ECDSAWithSHA256
+		return uint16(40)
+	case SigTypeECDSAWithSHA256, SigTypeEdDSASHA512Ed25519ph:
+		return uint16(64)
 	case SigTypeECDSAWithSHA384:
-		sigAlg = x509.ECDSAWithSHA384
+		return uint16(96)
 	case SigTypeECDSAWithSHA512:
-		sigAlg = x509.ECDSAWithSHA512
+		return uint16(132)
 	case SigTypeRSAWithSHA256:
-		sigAlg = x509.SHA256WithRSA
+		return uint16(256)
 	case SigTypeRSAWithSHA384:
-		sigAlg = x509.SHA384WithRSA
-	case SigTypeRSAWithSHA512:
-		sigAlg = x509.SHA512WithRSA
+		return uint16(384)
 	default:
-		return fmt.Errorf("unknown signature type: %d", s.SignatureType)
+		return uint16(512)
 	}
-
-	return checkSignature(cert, sigAlg, s.BodyBytes(), s.Signature)
 }
 
 func (s *File) String() string {
@@ -222,10 +322,10 @@ func (s *File) String() string {
 
 	// header
 	fmt.Fprintln(&b, "---------------------------")
-	fmt.Fprintf(&b, "Format: %q\n", s.Format)
-	fmt.Fprintf(&b, "SignatureType: %q\n", s.SignatureType)
-	fmt.Fprintf(&b, "FileType: %q\n", s.FileType)
-	fmt.Fprintf(&b, "ContentType: %q\n", s.ContentType)
+	fmt.Fprintf(&b, "Format: %d\n", s.Format)
+	fmt.Fprintf(&b, "SignatureType: %d\n", s.SignatureType)
+	fmt.Fprintf(&b, "FileType: %s\n", s.FileType)
+	fmt.Fprintf(&b, "ContentType: %s\n", s.ContentType)
 	fmt.Fprintf(&b, "Version: %q\n", bytes.Trim(s.Version, "\x00"))
 	fmt.Fprintf(&b, "SignerId: %q\n", s.SignerID)
 	fmt.Fprintf(&b, "---------------------------")
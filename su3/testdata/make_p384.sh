@@ -0,0 +1,48 @@
+#!/bin/sh
+# Writes p384.su3, an ECDSA-SHA384-P384 su3 signed by p384.crt, with openssl
+# and python instead of this package, following the su3 spec field by field.
+set -e
+cd "$(dirname "$0")"
+
+key=$(mktemp)
+trap 'rm -f "$key" body.tmp sig.tmp' EXIT
+openssl ecparam -name secp384r1 -genkey -noout -out "$key"
+openssl req -new -x509 -key "$key" -days 3650 -subj "/CN=test@mail.i2p" -out p384.crt
+
+python3 - body <<'PY'
+import struct
+version = b"1760648657".ljust(16, b"\0")
+signer = b"test@mail.i2p"
+content = b"<feed>su3 test</feed>\n"
+header = b"I2Psu3" + struct.pack(">BBHHBBBBQBBBB12x",
+    0, 0,             # unused, format
+    2, 96,            # ECDSA-SHA384-P384, signature length
+    0, len(version),  # unused, version length
+    0, len(signer),   # unused, signer ID length
+    len(content),
+    0, 1,             # unused, file type xml
+    0, 4)             # unused, content type news
+open("body.tmp", "wb").write(header + version + signer + content)
+PY
+
+openssl dgst -sha384 -sign "$key" -out sig.tmp body.tmp
+
+python3 - <<'PY'
+# DER SEQUENCE of two INTEGERs to fixed width r||s
+der = open("sig.tmp", "rb").read()
+def length(b, i):
+    n = b[i]
+    if n < 0x80:
+        return n, i + 1
+    k = n & 0x7f
+    return int.from_bytes(b[i+1:i+1+k], "big"), i + 1 + k
+_, i = length(der, 1)
+ints = []
+for _ in range(2):
+    assert der[i] == 0x02
+    n, i = length(der, i + 1)
+    ints.append(int.from_bytes(der[i:i+n], "big"))
+    i += n
+raw = b"".join(x.to_bytes(48, "big") for x in ints)
+open("p384.su3", "wb").write(open("body.tmp", "rb").read() + raw)
+PY
@@ -0,0 +1,104 @@
+package su3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ContentType describes what an su3 file is used for.
+type ContentType uint8
+
+var contentTypeNames = map[ContentType]string{
+	ContentTypeUnknown:   "unknown",
+	ContentTypeRouter:    "router",
+	ContentTypePlugin:    "plugin",
+	ContentTypeReseed:    "reseed",
+	ContentTypeNews:      "news",
+	ContentTypeBlocklist: "blocklist",
+}
+
+// String returns the name of the content type, or its number for values
+// this package doesn't know about.
+func (t ContentType) String() string {
+	if name, ok := contentTypeNames[t]; ok {
+		return name
+	}
+	return strconv.Itoa(int(t))
+}
+
+// Known reports whether t is defined by the su3 spec.
+func (t ContentType) Known() bool {
+	_, ok := contentTypeNames[t]
+	return ok
+}
+
+func (t ContentType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *ContentType) UnmarshalText(text []byte) error {
+	for k, name := range contentTypeNames {
+		if strings.EqualFold(name, string(text)) {
+			*t = k
+			return nil
+		}
+	}
+
+	n, err := strconv.ParseUint(string(text), 10, 8)
+	if nil != err {
+		return fmt.Errorf("unknown content type: %s", text)
+	}
+	*t = ContentType(n)
+
+	return nil
+}
+
+// FileType describes the encoding of the content of an su3 file.
+type FileType uint8
+
+var fileTypeNames = map[FileType]string{
+	FileTypeZIP:   "zip",
+	FileTypeXML:   "xml",
+	FileTypeHTML:  "html",
+	FileTypeXMLGZ: "xml.gz",
+	FileTypeTXTGZ: "txt.gz",
+	FileTypeDMG:   "dmg",
+	FileTypeEXE:   "exe",
+}
+
+// String returns the name of the file type, or its number for values this
+// package doesn't know about.
+func (t FileType) String() string {
+	if name, ok := fileTypeNames[t]; ok {
+		return name
+	}
+	return strconv.Itoa(int(t))
+}
+
+// Known reports whether t is defined by the su3 spec.
+func (t FileType) Known() bool {
+	_, ok := fileTypeNames[t]
+	return ok
+}
+
+func (t FileType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *FileType) UnmarshalText(text []byte) error {
+	for k, name := range fileTypeNames {
+		if strings.EqualFold(name, string(text)) {
+			*t = k
+			return nil
+		}
+	}
+
+	n, err := strconv.ParseUint(string(text), 10, 8)
+	if nil != err {
+		return fmt.Errorf("unknown file type: %s", text)
+	}
+	*t = FileType(n)
+
+	return nil
+}
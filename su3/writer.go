@@ -0,0 +1,92 @@
+package su3
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Writer signs and writes an su3 file to a stream without holding the
+// content in memory. The content length has to be known in advance since it
+// is part of the header.
+type Writer struct {
+	w         io.Writer
+	file      *File
+	signer    crypto.Signer
+	sigLength int
+	hash      hash.Hash
+	remaining uint64
+	closed    bool
+}
+
+// NewWriter writes the header of f to w and returns a Writer that accepts
+// exactly contentLength bytes of content. f.Content is ignored.
+func NewWriter(w io.Writer, f *File, contentLength uint64, signer crypto.Signer) (*Writer, error) {
+	hashType, err := sigTypeHash(f.SignatureType)
+	if nil != err {
+		return nil, err
+	}
+	sigLength, err := SignatureLength(f.SignatureType, signer.Public())
+	if nil != err {
+		return nil, err
+	}
+
+	sw := &Writer{
+		w:         w,
+		file:      f,
+		signer:    signer,
+		sigLength: sigLength,
+		hash:      hashType.New(),
+		remaining: contentLength,
+	}
+
+	if err := f.writeHeader(io.MultiWriter(w, sw.hash), contentLength); nil != err {
+		return nil, err
+	}
+
+	return sw, nil
+}
+
+// Write writes content to the su3 file.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("su3: write to closed writer")
+	}
+	if uint64(len(p)) > w.remaining {
+		return 0, errors.New("su3: content longer than declared length")
+	}
+
+	n, err := w.w.Write(p)
+	w.hash.Write(p[:n])
+	w.remaining -= uint64(n)
+
+	return n, err
+}
+
+// Close signs everything written so far and appends the signature. The
+// signature is also stored in the File passed to NewWriter. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.remaining > 0 {
+		return errors.New("su3: content shorter than declared length")
+	}
+
+	sig, err := signDigest(w.signer, w.file.SignatureType, w.hash.Sum(nil))
+	if nil != err {
+		return err
+	}
+	if len(sig) != w.sigLength {
+		return fmt.Errorf("%w: signer produced %d bytes, want %d", ErrSignatureLength, len(sig), w.sigLength)
+	}
+	w.file.Signature = sig
+
+	_, err = w.w.Write(sig)
+	return err
+}
@@ -0,0 +1,131 @@
+package su3
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// testFile is a news su3 whose version is already padded, so writing the
+// header doesn't change it.
+func testFile(content []byte) *File {
+	f := New()
+	f.SignatureType = SigTypeEdDSASHA512Ed25519ph
+	f.FileType = FileTypeXML
+	f.ContentType = ContentTypeNews
+	f.Version = []byte("1760648657\x00\x00\x00\x00\x00\x00")
+	f.SignerID = []byte("test@mail.i2p")
+	f.Content = content
+	return f
+}
+
+func testEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writeStream writes f through a Writer, in chunks of the given size.
+func writeStream(t *testing.T, f *File, signer crypto.Signer, chunk int) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, f, uint64(len(f.Content)), signer)
+	if nil != err {
+		t.Fatal(err)
+	}
+	for content := f.Content; len(content) > 0; {
+		n := chunk
+		if n > len(content) {
+			n = len(content)
+		}
+		if _, err := w.Write(content[:n]); nil != err {
+			t.Fatal(err)
+		}
+		content = content[n:]
+	}
+	if err := w.Close(); nil != err {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWriterMatchesMarshalBinary(t *testing.T) {
+	key := testEd25519Key(t)
+	content := bytes.Repeat([]byte("<feed>su3 test</feed>\n"), 1000)
+
+	// Ed25519 signatures are deterministic, so both have to be identical
+	f := testFile(content)
+	if err := f.Sign(key); nil != err {
+		t.Fatal(err)
+	}
+	want, err := f.MarshalBinary()
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	for _, chunk := range []int{1, 7, 4096, len(content)} {
+		if got := writeStream(t, testFile(content), key, chunk); !bytes.Equal(got, want) {
+			t.Errorf("writing in chunks of %d differs from MarshalBinary", chunk)
+		}
+	}
+}
+
+func TestWriterContentLength(t *testing.T) {
+	key := testEd25519Key(t)
+
+	w, err := NewWriter(ioutil.Discard, testFile(nil), 4, key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("12345")); nil == err {
+		t.Error("wrote more content than declared")
+	}
+	if _, err := w.Write([]byte("123")); nil != err {
+		t.Fatal(err)
+	}
+	if err := w.Close(); nil == err {
+		t.Error("closed with less content than declared")
+	}
+	if _, err := w.Write([]byte("4")); nil == err {
+		t.Error("wrote to a closed writer")
+	}
+}
+
+// longSigner adds a byte to every signature.
+type longSigner struct {
+	crypto.Signer
+}
+
+func (s longSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	sig, err := s.Signer.Sign(rand, digest, opts)
+	return append(sig, 0), err
+}
+
+func TestWriterSignatureLength(t *testing.T) {
+	signer := longSigner{testEd25519Key(t)}
+
+	var buf bytes.Buffer
+	f := testFile([]byte("content"))
+	w, err := NewWriter(&buf, f, uint64(len(f.Content)), signer)
+	if nil != err {
+		t.Fatal(err)
+	}
+	w.Write(f.Content)
+	written := buf.Len()
+	if err := w.Close(); !errors.Is(err, ErrSignatureLength) {
+		t.Errorf("closed with a %d byte signature: %v", ed25519.SignatureSize+1, err)
+	}
+	if buf.Len() != written {
+		t.Error("wrote a signature of the wrong length")
+	}
+
+	if err := testFile([]byte("content")).Sign(signer); !errors.Is(err, ErrSignatureLength) {
+		t.Errorf("signed with a %d byte signature: %v", ed25519.SignatureSize+1, err)
+	}
+}